@@ -0,0 +1,164 @@
+package heartbeat
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// BreakerState is the state of a CircuitBreaker.
+type BreakerState int
+
+const (
+	// BreakerClosed lets every check run.
+	BreakerClosed BreakerState = iota
+	// BreakerOpen short-circuits checks to StatusCritical until the cool-down ends.
+	BreakerOpen
+	// BreakerHalfOpen lets a single trial check run after the cool-down.
+	BreakerHalfOpen
+)
+
+// String implements the Stringer interface.
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerClosed:
+		return "closed"
+	case BreakerOpen:
+		return "open"
+	case BreakerHalfOpen:
+		return "half-open"
+	default:
+		return fmt.Sprintf("BreakerState(%d)", int(s))
+	}
+}
+
+// CircuitBreaker stops checking a dependency that keeps failing. After
+// Threshold consecutive Unknown or Critical results it opens, and checks are
+// short-circuited to StatusCritical without waiting on the dependency. Once
+// Cooldown has passed a single half-open trial check is let through: success
+// closes the breaker, failure opens it for another cool-down.
+//
+// The consecutive failures are counted across health requests, so each
+// dependency needs a breaker of its own, made once with NewCircuitBreaker
+// rather than on every request.
+type CircuitBreaker struct {
+	threshold int
+	cooldown  time.Duration
+
+	mu       sync.Mutex
+	state    BreakerState
+	failures int
+	openedAt time.Time
+	trial    bool
+	now      func() time.Time
+}
+
+// NewCircuitBreaker returns a closed breaker that opens after threshold
+// consecutive failures and stays open for cooldown.
+func NewCircuitBreaker(threshold int, cooldown time.Duration) *CircuitBreaker {
+	if threshold < 1 {
+		threshold = 1
+	}
+	return &CircuitBreaker{
+		threshold: threshold,
+		cooldown:  cooldown,
+		now:       time.Now,
+	}
+}
+
+// State returns the current state of the breaker.
+func (b *CircuitBreaker) State() BreakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state == BreakerOpen && b.now().Sub(b.openedAt) >= b.cooldown {
+		return BreakerHalfOpen
+	}
+	return b.state
+}
+
+// allow reports whether a check may run. When the cool-down has passed it
+// admits exactly one trial check until that check is recorded.
+func (b *CircuitBreaker) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case BreakerClosed:
+		return true
+	case BreakerOpen:
+		if b.now().Sub(b.openedAt) < b.cooldown {
+			return false
+		}
+		b.state = BreakerHalfOpen
+		b.trial = true
+		return true
+	default:
+		if b.trial {
+			return false
+		}
+		b.trial = true
+		return true
+	}
+}
+
+// record updates the breaker with the status of a check that was allowed to run.
+func (b *CircuitBreaker) record(status Status) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.trial = false
+	if !status.failing() {
+		b.state = BreakerClosed
+		b.failures = 0
+		return
+	}
+
+	b.failures++
+	if b.state == BreakerHalfOpen || b.failures >= b.threshold {
+		b.state = BreakerOpen
+		b.openedAt = b.now()
+	}
+}
+
+// release gives up a half-open trial without recording a result, as when the
+// health request was cancelled before the check completed.
+func (b *CircuitBreaker) release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.trial = false
+}
+
+// openResult is reported in place of a check while the breaker is open.
+func (b *CircuitBreaker) openResult() StatusResult {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	retryIn := b.cooldown - b.now().Sub(b.openedAt)
+	if retryIn < 0 {
+		retryIn = 0
+	}
+	return StatusResult{
+		Status:      StatusCritical,
+		Message:     fmt.Sprintf("circuit open after %d consecutive failures; next trial in %v", b.failures, retryIn.Round(time.Millisecond)),
+		CircuitOpen: true,
+	}
+}
+
+// checkWithBreaker runs the check through the dependency's circuit breaker.
+func checkWithBreaker(ctx context.Context, d DependencyDescriptor, check func() StatusResult) StatusResult {
+	if !d.Breaker.allow() {
+		return d.Breaker.openResult()
+	}
+
+	hsr := check()
+	if callerGone(ctx) {
+		// Cut short by the caller; the failure says nothing about the
+		// dependency. A check cut short by the handler's deadline still counts
+		// against it.
+		d.Breaker.release()
+		return hsr
+	}
+	d.Breaker.record(hsr.Status)
+	return hsr
+}
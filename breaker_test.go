@@ -0,0 +1,154 @@
+package heartbeat_test
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/twistingmercury/heartbeat"
+)
+
+func newCircuitBreaker(threshold int, cooldown time.Duration) (*heartbeat.CircuitBreaker, *fakeClock) {
+	clock := &fakeClock{t: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)}
+	b := heartbeat.NewCircuitBreaker(threshold, cooldown)
+	b.SetClock(clock.Now)
+	return b, clock
+}
+
+// switchableDep returns a dependency whose status is read from status on every
+// check, and counts its invocations.
+func switchableDep(b *heartbeat.CircuitBreaker, status *atomic.Int32) (heartbeat.DependencyDescriptor, *atomic.Int32) {
+	var calls atomic.Int32
+	return heartbeat.DependencyDescriptor{
+		Name: "flaky",
+		HandlerFunc: func() heartbeat.StatusResult {
+			calls.Add(1)
+			return heartbeat.StatusResult{Status: heartbeat.Status(status.Load())}
+		},
+		Breaker: b,
+	}, &calls
+}
+
+func TestCircuitBreakerOpensAfterThreshold(t *testing.T) {
+	b, _ := newCircuitBreaker(3, time.Minute)
+	var status atomic.Int32
+	status.Store(int32(heartbeat.StatusCritical))
+	d, calls := switchableDep(b, &status)
+
+	for i := 0; i < 3; i++ {
+		result := heartbeat.CheckDependency(context.Background(), d)
+		assert.False(t, result.CircuitOpen)
+	}
+	assert.Equal(t, heartbeat.BreakerOpen, b.State())
+
+	result := heartbeat.CheckDependency(context.Background(), d)
+	assert.Equal(t, heartbeat.StatusCritical, result.Status)
+	assert.True(t, result.CircuitOpen)
+	assert.Contains(t, result.Message, "circuit open after 3 consecutive failures")
+	assert.Equal(t, int32(3), calls.Load(), "open breaker must not run the check")
+}
+
+func TestCircuitBreakerWarningIsNotAFailure(t *testing.T) {
+	b, _ := newCircuitBreaker(1, time.Minute)
+	var status atomic.Int32
+	status.Store(int32(heartbeat.StatusWarning))
+	d, _ := switchableDep(b, &status)
+
+	heartbeat.CheckDependency(context.Background(), d)
+	assert.Equal(t, heartbeat.BreakerClosed, b.State())
+}
+
+func TestCircuitBreakerHalfOpenTrial(t *testing.T) {
+	b, clock := newCircuitBreaker(1, time.Minute)
+	var status atomic.Int32
+	status.Store(int32(heartbeat.StatusCritical))
+	d, calls := switchableDep(b, &status)
+
+	heartbeat.CheckDependency(context.Background(), d)
+	assert.Equal(t, heartbeat.BreakerOpen, b.State())
+
+	// A failed trial re-opens the breaker for another cool-down.
+	clock.Advance(time.Minute)
+	assert.Equal(t, heartbeat.BreakerHalfOpen, b.State())
+	result := heartbeat.CheckDependency(context.Background(), d)
+	assert.False(t, result.CircuitOpen)
+	assert.Equal(t, int32(2), calls.Load())
+	assert.Equal(t, heartbeat.BreakerOpen, b.State())
+
+	clock.Advance(30 * time.Second)
+	assert.True(t, heartbeat.CheckDependency(context.Background(), d).CircuitOpen)
+
+	// A successful trial closes it.
+	clock.Advance(30 * time.Second)
+	status.Store(int32(heartbeat.StatusOK))
+	result = heartbeat.CheckDependency(context.Background(), d)
+	assert.Equal(t, heartbeat.StatusOK, result.Status)
+	assert.Equal(t, heartbeat.BreakerClosed, b.State())
+}
+
+func TestCircuitBreakerAdmitsSingleTrial(t *testing.T) {
+	b, clock := newCircuitBreaker(1, time.Second)
+	release := make(chan struct{})
+	started := make(chan struct{}, 2)
+	failing := true
+	d := heartbeat.DependencyDescriptor{
+		Name: "slow",
+		HandlerFunc: func() heartbeat.StatusResult {
+			if failing {
+				return heartbeat.StatusResult{Status: heartbeat.StatusCritical}
+			}
+			started <- struct{}{}
+			<-release
+			return heartbeat.StatusResult{Status: heartbeat.StatusOK}
+		},
+		Breaker: b,
+	}
+
+	heartbeat.CheckDependency(context.Background(), d)
+	failing = false
+	clock.Advance(time.Second)
+
+	trial := make(chan heartbeat.StatusResult)
+	go func() { trial <- heartbeat.CheckDependency(context.Background(), d) }()
+	<-started
+
+	concurrent := heartbeat.CheckDependency(context.Background(), d)
+	assert.True(t, concurrent.CircuitOpen)
+
+	close(release)
+	assert.Equal(t, heartbeat.StatusOK, (<-trial).Status)
+	assert.Equal(t, heartbeat.BreakerClosed, b.State())
+}
+
+func TestCircuitBreakerShortCircuitsURLChecks(t *testing.T) {
+	ts := testServer(500, false)
+	defer ts.Close()
+
+	b, _ := newCircuitBreaker(1, time.Minute)
+	d := heartbeat.DependencyDescriptor{Name: "api", Connection: ts.URL, Breaker: b}
+
+	heartbeat.CheckDependency(context.Background(), d)
+	result := heartbeat.CheckDependency(context.Background(), d)
+	assert.True(t, result.CircuitOpen)
+	assert.Equal(t, ts.URL, result.Resource)
+}
+
+func TestCircuitBreakerIgnoresCancelledRequests(t *testing.T) {
+	b, _ := newCircuitBreaker(1, time.Minute)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	d := heartbeat.DependencyDescriptor{Name: "api", Connection: "http://127.0.0.1:1", Breaker: b}
+	result := heartbeat.CheckDependency(ctx, d)
+	assert.Equal(t, heartbeat.StatusCritical, result.Status)
+	assert.Equal(t, heartbeat.BreakerClosed, b.State())
+}
+
+func TestBreakerStateString(t *testing.T) {
+	assert.Equal(t, "closed", heartbeat.BreakerClosed.String())
+	assert.Equal(t, "open", heartbeat.BreakerOpen.String())
+	assert.Equal(t, "half-open", heartbeat.BreakerHalfOpen.String())
+	assert.Equal(t, "BreakerState(9)", heartbeat.BreakerState(9).String())
+}
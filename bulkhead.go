@@ -0,0 +1,195 @@
+package heartbeat
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// bulkhead limits the number of checks a handler runs at once, and keeps a
+// dependency whose previous check was abandoned, still running after its
+// timeout, from starting another one until that check returns.
+type bulkhead struct {
+	limit int
+	slots chan struct{} // nil when the number of checks is not limited
+
+	mu        sync.Mutex
+	stuck     map[string]stuckCheck
+	abandoned int
+}
+
+// stuckCheck records the abandoned checks of a dependency that are still running.
+type stuckCheck struct {
+	since time.Time
+	count int
+}
+
+// newBulkhead returns a bulkhead that runs at most limit checks at once. A limit
+// below 1 doesn't limit the number of checks but still tracks abandoned ones.
+func newBulkhead(limit int) *bulkhead {
+	b := &bulkhead{
+		limit: limit,
+		stuck: make(map[string]stuckCheck),
+	}
+	if limit > 0 {
+		b.slots = make(chan struct{}, limit)
+	}
+	return b
+}
+
+// Abandoned returns the number of abandoned checks that are still running.
+func (b *bulkhead) Abandoned() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.abandoned
+}
+
+// acquire reserves a slot for a check of the dependency, waiting up to wait for
+// one to become free. When the check may not start, the result to report in its
+// place is returned instead. A nil bulkhead admits every check.
+func (b *bulkhead) acquire(ctx context.Context, d DependencyDescriptor, wait time.Duration) (*slot, *StatusResult) {
+	if b == nil {
+		return nil, nil
+	}
+	key := bulkheadKey(d)
+
+	b.mu.Lock()
+	stuck, ok := b.stuck[key]
+	b.mu.Unlock()
+	if ok {
+		return nil, &StatusResult{
+			Status:  StatusCritical,
+			Message: fmt.Sprintf("previous check still running after %v; not starting another", time.Since(stuck.since).Round(time.Millisecond)),
+		}
+	}
+
+	if b.slots != nil {
+		timer := time.NewTimer(wait)
+		defer timer.Stop()
+		select {
+		case b.slots <- struct{}{}:
+		case <-ctx.Done():
+			return nil, &StatusResult{
+				Status:  StatusCritical,
+				Message: fmt.Sprintf("check not started: %v", ctx.Err()),
+			}
+		case <-timer.C:
+			return nil, &StatusResult{
+				Status:  StatusCritical,
+				Message: fmt.Sprintf("check not started: concurrency limit of %d reached", b.limit),
+			}
+		}
+	}
+	return &slot{b: b, key: key}, nil
+}
+
+func bulkheadKey(d DependencyDescriptor) string {
+	return d.Name + "\x00" + d.Connection
+}
+
+// slot is a running check's hold on the bulkhead. All methods are safe to call
+// on a nil slot.
+type slot struct {
+	b   *bulkhead
+	key string
+
+	mu        sync.Mutex
+	released  bool
+	exited    bool
+	abandoned bool
+	left      bool
+}
+
+// release frees the slot for another check. Only the first call has an effect.
+func (s *slot) release() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.releaseLocked()
+}
+
+func (s *slot) releaseLocked() {
+	if s.released {
+		return
+	}
+	s.released = true
+	if s.b.slots != nil {
+		<-s.b.slots
+	}
+}
+
+// abandon marks the check as abandoned unless it has already returned, and
+// reports whether it was. A nil slot is always abandoned.
+func (s *slot) abandon() bool {
+	if s == nil {
+		return true
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.exited {
+		return false
+	}
+	s.abandoned = true
+
+	s.b.mu.Lock()
+	stuck := s.b.stuck[s.key]
+	if stuck.count == 0 {
+		stuck.since = time.Now()
+	}
+	stuck.count++
+	s.b.stuck[s.key] = stuck
+	s.b.abandoned++
+	s.b.mu.Unlock()
+	return true
+}
+
+// leave hands the slot to the check when the caller stops waiting for it
+// before its timeout, and reports whether the check was still running. Unlike
+// an abandoned check, it doesn't keep the dependency from being checked again.
+// A nil slot is always left.
+func (s *slot) leave() bool {
+	if s == nil {
+		return true
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.exited {
+		return false
+	}
+	s.left = true
+	return true
+}
+
+// exit is called when the check returns. An abandoned or left check gives back
+// its slot.
+func (s *slot) exit() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.exited = true
+	if s.left {
+		s.releaseLocked()
+		return
+	}
+	if !s.abandoned {
+		return
+	}
+
+	s.b.mu.Lock()
+	stuck := s.b.stuck[s.key]
+	stuck.count--
+	if stuck.count <= 0 {
+		delete(s.b.stuck, s.key)
+	} else {
+		s.b.stuck[s.key] = stuck
+	}
+	s.b.abandoned--
+	s.b.mu.Unlock()
+
+	s.releaseLocked()
+}
@@ -0,0 +1,135 @@
+package heartbeat_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/twistingmercury/heartbeat"
+)
+
+func healthResponse(t *testing.T, r *gin.Engine, edits ...func(*http.Request)) heartbeat.Response {
+	t.Helper()
+	resp := serveHealth(r, context.Background(), edits...)
+	var hcr heartbeat.Response
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &hcr))
+	return hcr
+}
+
+func TestMaxConcurrencyLimitsRunningChecks(t *testing.T) {
+	var running, peak atomic.Int32
+	check := func() heartbeat.StatusResult {
+		n := running.Add(1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(50 * time.Millisecond)
+		running.Add(-1)
+		return heartbeat.StatusResult{Status: heartbeat.StatusOK}
+	}
+	deps := []heartbeat.DependencyDescriptor{
+		{Name: "a", HandlerFunc: check},
+		{Name: "b", HandlerFunc: check},
+		{Name: "c", HandlerFunc: check},
+		{Name: "d", HandlerFunc: check},
+	}
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/health", heartbeat.NewHandler("unit-test", deps, heartbeat.WithMaxConcurrency(2)))
+
+	hcr := healthResponse(t, r)
+	assert.Equal(t, heartbeat.StatusOK, hcr.Status)
+	assert.Equal(t, int32(2), peak.Load())
+}
+
+func TestMaxConcurrencyDoesNotRestartStuckChecks(t *testing.T) {
+	var calls atomic.Int32
+	release := make(chan struct{})
+	deps := []heartbeat.DependencyDescriptor{
+		{Name: "stuck", Timeout: 50 * time.Millisecond, HandlerFunc: func() heartbeat.StatusResult {
+			if calls.Add(1) == 1 {
+				<-release
+			}
+			return heartbeat.StatusResult{Status: heartbeat.StatusOK}
+		}},
+	}
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/health", heartbeat.NewHandler("unit-test", deps, heartbeat.WithMaxConcurrency(0)))
+
+	hcr := healthResponse(t, r)
+	assert.Contains(t, hcr.Dependencies[0].Message, "custom handler timeout")
+	assert.Equal(t, 1, hcr.AbandonedChecks)
+
+	hcr = healthResponse(t, r)
+	assert.Equal(t, heartbeat.StatusCritical, hcr.Status)
+	assert.Contains(t, hcr.Dependencies[0].Message, "previous check still running")
+	assert.Equal(t, 1, hcr.AbandonedChecks)
+	assert.Equal(t, int32(1), calls.Load())
+
+	close(release)
+	assert.Eventually(t, func() bool {
+		hcr = healthResponse(t, r)
+		return hcr.Status == heartbeat.StatusOK
+	}, time.Second, 10*time.Millisecond)
+	assert.Equal(t, 0, hcr.AbandonedChecks)
+}
+
+func TestMaxConcurrencyStuckChecksKeepTheirSlots(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	deps := []heartbeat.DependencyDescriptor{
+		{Name: "stuck", Timeout: 50 * time.Millisecond, HandlerFunc: func() heartbeat.StatusResult {
+			<-release
+			return heartbeat.StatusResult{Status: heartbeat.StatusOK}
+		}},
+	}
+	others := []heartbeat.DependencyDescriptor{
+		{Name: "other", Timeout: 50 * time.Millisecond, HandlerFunc: func() heartbeat.StatusResult {
+			return heartbeat.StatusResult{Status: heartbeat.StatusOK}
+		}},
+	}
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	h := heartbeat.NewHandler("unit-test", append(deps, others...), heartbeat.WithMaxConcurrency(1))
+	r.GET("/health", h)
+
+	// Whichever check runs second waits for the slot, which the stuck check
+	// never gives back.
+	hcr := healthResponse(t, r)
+	assert.Equal(t, heartbeat.StatusCritical, hcr.Status)
+	assert.Equal(t, 1, hcr.AbandonedChecks)
+
+	hcr = healthResponse(t, r)
+	assert.Contains(t, hcr.Dependencies[0].Message, "previous check still running")
+	assert.Contains(t, hcr.Dependencies[1].Message, "concurrency limit of 1 reached")
+}
+
+func TestWithoutMaxConcurrencyAbandonedChecksAreNotReported(t *testing.T) {
+	deps := []heartbeat.DependencyDescriptor{
+		{Name: "slow", Timeout: 10 * time.Millisecond, HandlerFunc: func() heartbeat.StatusResult {
+			time.Sleep(100 * time.Millisecond)
+			return heartbeat.StatusResult{Status: heartbeat.StatusOK}
+		}},
+	}
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/health", heartbeat.NewHandler("unit-test", deps))
+
+	resp := serveHealth(r, context.Background())
+	assert.Equal(t, http.StatusServiceUnavailable, resp.Code)
+	assert.NotContains(t, resp.Body.String(), "abandoned_checks")
+}
@@ -0,0 +1,286 @@
+package heartbeat
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefaultCgroupRoot is the mount point of the cgroup filesystem inside a container.
+const DefaultCgroupRoot = "/sys/fs/cgroup"
+
+// CgroupOptions configures a cgroup resource pressure check. A zero threshold
+// selects the default for that threshold.
+type CgroupOptions struct {
+	// Root is the cgroup mount point. Defaults to DefaultCgroupRoot.
+	Root string
+
+	// MemoryWarning and MemoryCritical are the fractions of the memory limit in
+	// use (working set, excluding inactive page cache). Defaults: 0.80 and 0.95.
+	MemoryWarning  float64
+	MemoryCritical float64
+
+	// ThrottleWarning and ThrottleCritical are the fractions of CPU scheduler
+	// periods that were throttled since the previous check. Defaults: 0.25 and 0.50.
+	ThrottleWarning  float64
+	ThrottleCritical float64
+
+	// PressureWarning and PressureCritical are PSI "some avg10" percentages for
+	// cpu, memory and io (cgroup v2 only). Defaults: 20 and 50.
+	PressureWarning  float64
+	PressureCritical float64
+}
+
+func (o *CgroupOptions) setDefaults() {
+	if o.Root == "" {
+		o.Root = DefaultCgroupRoot
+	}
+	if o.MemoryWarning == 0 {
+		o.MemoryWarning = 0.80
+	}
+	if o.MemoryCritical == 0 {
+		o.MemoryCritical = 0.95
+	}
+	if o.ThrottleWarning == 0 {
+		o.ThrottleWarning = 0.25
+	}
+	if o.ThrottleCritical == 0 {
+		o.ThrottleCritical = 0.50
+	}
+	if o.PressureWarning == 0 {
+		o.PressureWarning = 20
+	}
+	if o.PressureCritical == 0 {
+		o.PressureCritical = 50
+	}
+}
+
+// cgroupV1UnlimitedMemory is the smallest limit_in_bytes value that cgroup v1
+// reports for a cgroup without a memory limit (PAGE_COUNTER_MAX rounded to pages).
+const cgroupV1UnlimitedMemory = 1 << 62
+
+// CgroupCheck returns a StatusHandlerFunc that reports memory usage against the
+// limit, CPU throttling and PSI pressure for the container's cgroup. Both cgroup
+// v1 and v2 hierarchies are supported. CPU throttling is measured as the delta
+// between consecutive checks, so the returned func should be reused; the first
+// check only records the baseline.
+func CgroupCheck(opts CgroupOptions) StatusHandlerFunc {
+	opts.setDefaults()
+
+	var mu sync.Mutex
+	var prevPeriods, prevThrottled uint64
+	var baseline bool
+
+	return func() StatusResult {
+		hsr := StatusResult{Resource: opts.Root, Status: StatusOK}
+		var msgs []string
+		found := false
+
+		raise := func(s Status, msg string) {
+			if s.Severity() > hsr.Status.Severity() {
+				hsr.Status = s
+			}
+			msgs = append(msgs, msg)
+		}
+
+		v2 := fileExists(filepath.Join(opts.Root, "cgroup.controllers"))
+
+		// Memory
+		if usage, limit, ok := cgroupMemory(opts.Root, v2); ok {
+			found = true
+			if limit > 0 {
+				ratio := float64(usage) / float64(limit)
+				msg := fmt.Sprintf("memory %.1f%% of limit", ratio*100)
+				switch {
+				case ratio >= opts.MemoryCritical:
+					raise(StatusCritical, msg)
+				case ratio >= opts.MemoryWarning:
+					raise(StatusWarning, msg)
+				}
+			}
+		}
+
+		// CPU throttling
+		if periods, throttled, ok := cgroupCPUStat(opts.Root, v2); ok {
+			found = true
+			mu.Lock()
+			// Without a baseline, or after the counters were reset, the
+			// counters cover the whole life of the cgroup, not the interval.
+			var dp, dt uint64
+			if baseline && periods >= prevPeriods && throttled >= prevThrottled {
+				dp, dt = periods-prevPeriods, throttled-prevThrottled
+			}
+			prevPeriods, prevThrottled, baseline = periods, throttled, true
+			mu.Unlock()
+
+			if dp > 0 {
+				ratio := float64(dt) / float64(dp)
+				msg := fmt.Sprintf("cpu throttled in %.1f%% of periods", ratio*100)
+				switch {
+				case ratio >= opts.ThrottleCritical:
+					raise(StatusCritical, msg)
+				case ratio >= opts.ThrottleWarning:
+					raise(StatusWarning, msg)
+				}
+			}
+		}
+
+		// Pressure stall information
+		if v2 {
+			for _, res := range []string{"cpu", "memory", "io"} {
+				avg10, ok := cgroupPressure(filepath.Join(opts.Root, res+".pressure"))
+				if !ok {
+					continue
+				}
+				found = true
+				msg := fmt.Sprintf("%s pressure some avg10=%.2f", res, avg10)
+				switch {
+				case avg10 >= opts.PressureCritical:
+					raise(StatusCritical, msg)
+				case avg10 >= opts.PressureWarning:
+					raise(StatusWarning, msg)
+				}
+			}
+		}
+
+		switch {
+		case !found:
+			hsr.Status = StatusCritical
+			hsr.Message = fmt.Sprintf("no cgroup data found under %s", opts.Root)
+		case len(msgs) == 0:
+			hsr.Message = "ok"
+		default:
+			hsr.Message = strings.Join(msgs, "; ")
+		}
+		return hsr
+	}
+}
+
+// cgroupMemory returns the working set and the memory limit. A limit of 0 means
+// the cgroup is unlimited.
+func cgroupMemory(root string, v2 bool) (usage, limit uint64, ok bool) {
+	var usageFile, limitFile, statFile, inactiveKey string
+	if v2 {
+		usageFile = filepath.Join(root, "memory.current")
+		limitFile = filepath.Join(root, "memory.max")
+		statFile = filepath.Join(root, "memory.stat")
+		inactiveKey = "inactive_file"
+	} else {
+		usageFile = filepath.Join(root, "memory", "memory.usage_in_bytes")
+		limitFile = filepath.Join(root, "memory", "memory.limit_in_bytes")
+		statFile = filepath.Join(root, "memory", "memory.stat")
+		inactiveKey = "total_inactive_file"
+	}
+
+	usage, err := readUint(usageFile)
+	if err != nil {
+		return 0, 0, false
+	}
+
+	raw, err := os.ReadFile(limitFile)
+	if err != nil {
+		return 0, 0, false
+	}
+	if s := strings.TrimSpace(string(raw)); s != "max" {
+		limit, err = strconv.ParseUint(s, 10, 64)
+		if err != nil {
+			return 0, 0, false
+		}
+		if limit >= cgroupV1UnlimitedMemory {
+			limit = 0
+		}
+	}
+
+	if stat, err := readKeyValues(statFile); err == nil {
+		if inactive, ok := stat[inactiveKey]; ok && inactive < usage {
+			usage -= inactive
+		}
+	}
+	return usage, limit, true
+}
+
+// cgroupCPUStat returns the cumulative number of enforcement periods and the
+// number of those periods in which the cgroup was throttled.
+func cgroupCPUStat(root string, v2 bool) (periods, throttled uint64, ok bool) {
+	candidates := []string{filepath.Join(root, "cpu.stat")}
+	if !v2 {
+		candidates = []string{
+			filepath.Join(root, "cpu", "cpu.stat"),
+			filepath.Join(root, "cpu,cpuacct", "cpu.stat"),
+		}
+	}
+	for _, file := range candidates {
+		stat, err := readKeyValues(file)
+		if err != nil {
+			continue
+		}
+		p, okP := stat["nr_periods"]
+		t, okT := stat["nr_throttled"]
+		if okP && okT {
+			return p, t, true
+		}
+	}
+	return 0, 0, false
+}
+
+// cgroupPressure returns the "some avg10" value of a PSI file.
+func cgroupPressure(file string) (float64, bool) {
+	f, err := os.Open(file)
+	if err != nil {
+		return 0, false
+	}
+	defer func() {
+		_ = f.Close() // Error intentionally ignored - read-only file
+	}()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || fields[0] != "some" {
+			continue
+		}
+		for _, field := range fields[1:] {
+			if v, found := strings.CutPrefix(field, "avg10="); found {
+				avg10, err := strconv.ParseFloat(v, 64)
+				return avg10, err == nil
+			}
+		}
+	}
+	return 0, false
+}
+
+func readUint(file string) (uint64, error) {
+	raw, err := os.ReadFile(file)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseUint(strings.TrimSpace(string(raw)), 10, 64)
+}
+
+// readKeyValues parses files made of "key value" lines, such as cpu.stat and memory.stat.
+func readKeyValues(file string) (map[string]uint64, error) {
+	raw, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	values := make(map[string]uint64)
+	for _, line := range strings.Split(string(raw), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			continue
+		}
+		if v, err := strconv.ParseUint(fields[1], 10, 64); err == nil {
+			values[fields[0]] = v
+		}
+	}
+	return values, nil
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
@@ -0,0 +1,180 @@
+package heartbeat_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/twistingmercury/heartbeat"
+)
+
+func writeCgroupFiles(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	}
+}
+
+func TestCgroupCheckV2(t *testing.T) {
+	tests := []struct {
+		name           string
+		files          map[string]string
+		expectedStatus heartbeat.Status
+		messageContain string
+	}{
+		{
+			name: "healthy",
+			files: map[string]string{
+				"memory.current":  "500\n",
+				"memory.max":      "1000\n",
+				"memory.stat":     "anon 400\ninactive_file 100\n",
+				"cpu.stat":        "usage_usec 10\nnr_periods 100\nnr_throttled 1\n",
+				"memory.pressure": "some avg10=0.00 avg60=0.00 avg300=0.00 total=0\nfull avg10=0.00 avg60=0.00 avg300=0.00 total=0\n",
+			},
+			expectedStatus: heartbeat.StatusOK,
+			messageContain: "ok",
+		},
+		{
+			name: "memory near limit excluding inactive file cache",
+			files: map[string]string{
+				"memory.current": "950\n",
+				"memory.max":     "1000\n",
+				"memory.stat":    "inactive_file 100\n",
+			},
+			expectedStatus: heartbeat.StatusWarning,
+			messageContain: "memory 85.0% of limit",
+		},
+		{
+			name: "memory at limit",
+			files: map[string]string{
+				"memory.current": "990\n",
+				"memory.max":     "1000\n",
+			},
+			expectedStatus: heartbeat.StatusCritical,
+			messageContain: "memory 99.0% of limit",
+		},
+		{
+			name: "unlimited memory is ignored",
+			files: map[string]string{
+				"memory.current": "990\n",
+				"memory.max":     "max\n",
+			},
+			expectedStatus: heartbeat.StatusOK,
+			messageContain: "ok",
+		},
+		{
+			name: "cpu throttling before a baseline is ignored",
+			files: map[string]string{
+				"cpu.stat": "nr_periods 100\nnr_throttled 60\n",
+			},
+			expectedStatus: heartbeat.StatusOK,
+			messageContain: "ok",
+		},
+		{
+			name: "io pressure",
+			files: map[string]string{
+				"io.pressure": "some avg10=25.50 avg60=10.00 avg300=1.00 total=100\n",
+			},
+			expectedStatus: heartbeat.StatusWarning,
+			messageContain: "io pressure some avg10=25.50",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			tt.files["cgroup.controllers"] = "cpu io memory\n"
+			writeCgroupFiles(t, root, tt.files)
+
+			result := heartbeat.CgroupCheck(heartbeat.CgroupOptions{Root: root})()
+			assert.Equal(t, tt.expectedStatus, result.Status)
+			assert.Contains(t, result.Message, tt.messageContain)
+			assert.Equal(t, root, result.Resource)
+		})
+	}
+}
+
+func TestCgroupCheckV1(t *testing.T) {
+	root := t.TempDir()
+	writeCgroupFiles(t, root, map[string]string{
+		"memory/memory.usage_in_bytes": "900\n",
+		"memory/memory.limit_in_bytes": "1000\n",
+		"memory/memory.stat":           "total_inactive_file 0\n",
+		"cpu,cpuacct/cpu.stat":         "nr_periods 10\nnr_throttled 0\nthrottled_time 0\n",
+	})
+
+	result := heartbeat.CgroupCheck(heartbeat.CgroupOptions{Root: root})()
+	assert.Equal(t, heartbeat.StatusWarning, result.Status)
+	assert.Contains(t, result.Message, "memory 90.0% of limit")
+}
+
+func TestCgroupCheckV1UnlimitedMemory(t *testing.T) {
+	root := t.TempDir()
+	writeCgroupFiles(t, root, map[string]string{
+		"memory/memory.usage_in_bytes": "900\n",
+		"memory/memory.limit_in_bytes": "9223372036854771712\n",
+	})
+
+	result := heartbeat.CgroupCheck(heartbeat.CgroupOptions{Root: root})()
+	assert.Equal(t, heartbeat.StatusOK, result.Status)
+}
+
+func TestCgroupCheckThrottleDelta(t *testing.T) {
+	root := t.TempDir()
+	writeCgroupFiles(t, root, map[string]string{
+		"cgroup.controllers": "cpu\n",
+		"cpu.stat":           "nr_periods 100\nnr_throttled 80\n",
+	})
+
+	check := heartbeat.CgroupCheck(heartbeat.CgroupOptions{Root: root})
+	// The first check records the baseline: 80% over the cgroup's life says
+	// nothing about the current throttling.
+	assert.Equal(t, heartbeat.StatusOK, check().Status)
+
+	writeCgroupFiles(t, root, map[string]string{
+		"cpu.stat": "nr_periods 200\nnr_throttled 140\n",
+	})
+	result := check()
+	assert.Equal(t, heartbeat.StatusCritical, result.Status)
+	assert.Contains(t, result.Message, "cpu throttled in 60.0% of periods")
+
+	// Only 1 of the 100 periods since the previous check was throttled.
+	writeCgroupFiles(t, root, map[string]string{
+		"cpu.stat": "nr_periods 300\nnr_throttled 141\n",
+	})
+	assert.Equal(t, heartbeat.StatusOK, check().Status)
+
+	// Reset counters, as after a container restart, start a new baseline.
+	writeCgroupFiles(t, root, map[string]string{
+		"cpu.stat": "nr_periods 50\nnr_throttled 50\n",
+	})
+	assert.Equal(t, heartbeat.StatusOK, check().Status)
+}
+
+func TestCgroupCheckCustomThresholds(t *testing.T) {
+	root := t.TempDir()
+	writeCgroupFiles(t, root, map[string]string{
+		"cgroup.controllers": "memory\n",
+		"memory.current":     "500\n",
+		"memory.max":         "1000\n",
+	})
+
+	result := heartbeat.CgroupCheck(heartbeat.CgroupOptions{
+		Root:           root,
+		MemoryWarning:  0.4,
+		MemoryCritical: 0.6,
+	})()
+	assert.Equal(t, heartbeat.StatusWarning, result.Status)
+}
+
+func TestCgroupCheckMissingRoot(t *testing.T) {
+	root := filepath.Join(t.TempDir(), "missing")
+
+	result := heartbeat.CgroupCheck(heartbeat.CgroupOptions{Root: root})()
+	assert.Equal(t, heartbeat.StatusCritical, result.Status)
+	assert.Contains(t, result.Message, "no cgroup data found")
+}
@@ -0,0 +1,131 @@
+package heartbeat
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// coalescer shares a single in-flight check run between concurrent health
+// requests, and optionally reuses a completed run for a short window.
+type coalescer struct {
+	reuse time.Duration
+
+	mu       sync.Mutex
+	inflight *checkRun
+	last     *checkRun
+}
+
+// checkFunc runs the checks of the given dependencies, passing each result to
+// onResult, when set, as soon as it is known.
+type checkFunc func(ctx context.Context, deps []DependencyDescriptor, onResult func(int, StatusResult)) (Status, []StatusResult)
+
+// checkRun is a single execution of checkDeps shared by all of its callers.
+type checkRun struct {
+	done     chan struct{}
+	status   Status
+	results  []StatusResult
+	finished time.Time
+
+	mu        sync.Mutex
+	partial   []StatusResult // results of the checks completed so far
+	completed []bool
+}
+
+// record stores the result of a completed check.
+func (run *checkRun) record(i int, hsr StatusResult) {
+	run.mu.Lock()
+	defer run.mu.Unlock()
+	run.partial[i] = hsr
+	run.completed[i] = true
+}
+
+// timedOutResults returns the results of the checks completed so far, with
+// those still pending reported as timed out.
+func (run *checkRun) timedOutResults(deps []DependencyDescriptor, de deadlineExceeded) (Status, []StatusResult) {
+	run.mu.Lock()
+	defer run.mu.Unlock()
+
+	status := StatusNotSet
+	results := make([]StatusResult, len(deps))
+	for i, d := range deps {
+		if run.completed[i] {
+			results[i] = run.partial[i]
+		} else {
+			results[i] = timedOutResult(d, de, de.budget)
+		}
+		if results[i].Status.Severity() > status.Severity() {
+			status = results[i].Status
+		}
+	}
+	return status, results
+}
+
+// do returns the results of the in-flight run, or of a run that finished within
+// the reuse window, starting a new run when there is neither. The run is
+// detached from ctx so that one caller going away doesn't cancel it for the
+// others. A caller whose deadline passes stops waiting and gets the checks
+// completed so far, with the others timed out; a caller whose ctx ends
+// otherwise gets cancelled results.
+func (co *coalescer) do(ctx context.Context, deps []DependencyDescriptor, check checkFunc) (Status, []StatusResult) {
+	co.mu.Lock()
+	run := co.inflight
+	switch {
+	case run != nil:
+	case co.last != nil && time.Since(co.last.finished) < co.reuse:
+		run = co.last
+	default:
+		run = &checkRun{
+			done:      make(chan struct{}),
+			partial:   make([]StatusResult, len(deps)),
+			completed: make([]bool, len(deps)),
+		}
+		co.inflight = run
+		go co.execute(context.WithoutCancel(ctx), run, deps, check)
+	}
+	co.mu.Unlock()
+
+	select {
+	case <-run.done:
+		// Callers own their results; don't let them share the backing array.
+		return run.status, append([]StatusResult(nil), run.results...)
+	case <-ctx.Done():
+		var de deadlineExceeded
+		if errors.As(context.Cause(ctx), &de) {
+			return run.timedOutResults(deps, de)
+		}
+		return cancelledResults(ctx, deps)
+	}
+}
+
+func (co *coalescer) execute(ctx context.Context, run *checkRun, deps []DependencyDescriptor, check checkFunc) {
+	run.status, run.results = check(ctx, deps, run.record)
+	run.finished = time.Now()
+
+	co.mu.Lock()
+	co.inflight = nil
+	co.last = run
+	co.mu.Unlock()
+	close(run.done)
+}
+
+// cancelledResults reports every dependency as Critical because the request
+// ended before the shared run completed.
+func cancelledResults(ctx context.Context, deps []DependencyDescriptor) (Status, []StatusResult) {
+	results := make([]StatusResult, len(deps))
+	for i, d := range deps {
+		results[i] = StatusResult{
+			Status:   StatusCritical,
+			Name:     d.Name,
+			Type:     d.Type,
+			Resource: d.Name,
+			Message:  fmt.Sprintf("request cancelled: %v", ctx.Err()),
+		}
+	}
+	if len(deps) == 0 {
+		return StatusNotSet, results
+	}
+	return StatusCritical, results
+}
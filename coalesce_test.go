@@ -0,0 +1,131 @@
+package heartbeat_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/twistingmercury/heartbeat"
+)
+
+// countingDeps returns a dependency that takes delay to check and counts its executions.
+func countingDeps(delay time.Duration) ([]heartbeat.DependencyDescriptor, *atomic.Int32) {
+	var calls atomic.Int32
+	return []heartbeat.DependencyDescriptor{
+		{Name: "slow", Type: "Custom", HandlerFunc: func() heartbeat.StatusResult {
+			calls.Add(1)
+			time.Sleep(delay)
+			return heartbeat.StatusResult{Status: heartbeat.StatusOK, Message: "ok"}
+		}},
+	}, &calls
+}
+
+// healthHandler returns an engine serving the handler for deps at /health.
+func healthHandler(deps []heartbeat.DependencyDescriptor, opts ...heartbeat.Option) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/health", heartbeat.NewHandler("unit-test", deps, opts...))
+	return r
+}
+
+// serveHealth serves a GET request for /health, changed by each of edits.
+func serveHealth(r *gin.Engine, ctx context.Context, edits ...func(*http.Request)) *httptest.ResponseRecorder {
+	resp := httptest.NewRecorder()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, "/health", nil)
+	for _, edit := range edits {
+		edit(req)
+	}
+	r.ServeHTTP(resp, req)
+	return resp
+}
+
+// withTarget requests target, such as "/health?timeout=100ms", instead.
+func withTarget(target string) func(*http.Request) {
+	return func(req *http.Request) {
+		req.URL, _ = url.Parse(target)
+	}
+}
+
+// withHeader sets the request header name to value.
+func withHeader(name, value string) func(*http.Request) {
+	return func(req *http.Request) {
+		req.Header.Set(name, value)
+	}
+}
+
+func TestCoalescingSharesInFlightRun(t *testing.T) {
+	deps, calls := countingDeps(200 * time.Millisecond)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/health", heartbeat.NewHandler("unit-test", deps, heartbeat.WithCoalescing(0)))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resp := serveHealth(r, context.Background())
+			assert.Equal(t, http.StatusOK, resp.Code)
+
+			var hcr heartbeat.Response
+			assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), &hcr))
+			assert.Len(t, hcr.Dependencies, 1)
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, int32(1), calls.Load())
+}
+
+func TestCoalescingReuseWindow(t *testing.T) {
+	deps, calls := countingDeps(0)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/health", heartbeat.NewHandler("unit-test", deps, heartbeat.WithCoalescing(time.Minute)))
+
+	for i := 0; i < 3; i++ {
+		serveHealth(r, context.Background())
+	}
+	assert.Equal(t, int32(1), calls.Load())
+}
+
+func TestCoalescingWithoutReuseRunsAgain(t *testing.T) {
+	deps, calls := countingDeps(0)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/health", heartbeat.NewHandler("unit-test", deps, heartbeat.WithCoalescing(0)))
+
+	serveHealth(r, context.Background())
+	serveHealth(r, context.Background())
+	assert.Equal(t, int32(2), calls.Load())
+}
+
+func TestCoalescingCancelledWaiter(t *testing.T) {
+	deps, calls := countingDeps(300 * time.Millisecond)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/health", heartbeat.NewHandler("unit-test", deps, heartbeat.WithCoalescing(time.Minute)))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	resp := serveHealth(r, ctx)
+	assert.Equal(t, http.StatusServiceUnavailable, resp.Code)
+	assert.Contains(t, resp.Body.String(), "request cancelled")
+
+	// The shared run was not cancelled with the first caller and is reused.
+	resp = serveHealth(r, context.Background())
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, int32(1), calls.Load())
+}
@@ -0,0 +1,84 @@
+package heartbeat
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// TimeoutQueryParam is the query parameter that overrides the handler's
+// deadline for a single request, e.g. "/health?timeout=800ms".
+const TimeoutQueryParam = "timeout"
+
+// deadlineExceeded is the cause of the context cancellation when the
+// handler's overall deadline passes. The deadline is requested when it was
+// shortened by the timeout query parameter.
+type deadlineExceeded struct {
+	budget    time.Duration
+	requested bool
+}
+
+func (e deadlineExceeded) Error() string {
+	return fmt.Sprintf("health check deadline of %v exceeded", e.budget)
+}
+
+// withDeadline bounds ctx by the request's deadline. The cause of the
+// resulting cancellation identifies the deadline to checkDeps.
+func withDeadline(ctx context.Context, de deadlineExceeded) (context.Context, context.CancelFunc) {
+	if de.budget <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeoutCause(ctx, de.budget, de)
+}
+
+// callerGone reports whether ctx was cancelled by the caller, by going away or
+// by the deadline it requested, rather than by the handler's deadline. Results
+// cut short by the caller say nothing about the dependencies.
+func callerGone(ctx context.Context) bool {
+	var de deadlineExceeded
+	return ctx.Err() != nil && (!errors.As(context.Cause(ctx), &de) || de.requested)
+}
+
+// requestDeadline returns the deadline for a request: the value of the timeout
+// query parameter when it's valid, otherwise the configured deadline. The
+// parameter is a Go duration ("800ms", "2s") or a number of seconds ("0.8").
+// It is ignored without a configured deadline and can only shorten one, so
+// that callers can't hold the handler longer than intended.
+func requestDeadline(query string, configured time.Duration) deadlineExceeded {
+	de := deadlineExceeded{budget: configured}
+	if query == "" || configured <= 0 {
+		return de
+	}
+	d, err := time.ParseDuration(query)
+	if err != nil {
+		secs, err := strconv.ParseFloat(query, 64)
+		if err != nil {
+			return de
+		}
+		d = time.Duration(secs * float64(time.Second))
+	}
+	if d <= 0 || d >= configured {
+		return de
+	}
+	return deadlineExceeded{budget: d, requested: true}
+}
+
+// timedOutResult reports a dependency whose check was still pending when the
+// handler's deadline passed.
+func timedOutResult(d DependencyDescriptor, de deadlineExceeded, elapsed time.Duration) StatusResult {
+	resource := d.Connection
+	if d.HandlerFunc != nil || resource == "" {
+		resource = d.Name
+	}
+	return StatusResult{
+		Status:          StatusCritical,
+		Name:            d.Name,
+		Type:            d.Type,
+		Resource:        resource,
+		RequestDuration: float64(elapsed.Microseconds()) / 1000,
+		Message:         "check timed out: " + de.Error(),
+		timeout:         checkTimeout(d),
+	}
+}
@@ -0,0 +1,190 @@
+package heartbeat_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/twistingmercury/heartbeat"
+)
+
+func slowAndFastDeps() []heartbeat.DependencyDescriptor {
+	return []heartbeat.DependencyDescriptor{
+		{Name: "fast", Type: "Custom", HandlerFunc: func() heartbeat.StatusResult {
+			return heartbeat.StatusResult{Status: heartbeat.StatusOK, Message: "ok"}
+		}},
+		{Name: "slow", Type: "Custom", HandlerFunc: func() heartbeat.StatusResult {
+			time.Sleep(2 * time.Second)
+			return heartbeat.StatusResult{Status: heartbeat.StatusOK, Message: "ok"}
+		}},
+	}
+}
+
+func TestDeadlineReportsPendingChecksAsTimedOut(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/health", heartbeat.NewHandler("unit-test", slowAndFastDeps(), heartbeat.WithDeadline(100*time.Millisecond)))
+
+	st := time.Now()
+	resp := serveHealth(r, context.Background())
+	assert.Less(t, time.Since(st), time.Second)
+	assert.Equal(t, http.StatusServiceUnavailable, resp.Code)
+
+	var hcr heartbeat.Response
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &hcr))
+	assert.Equal(t, heartbeat.StatusCritical, hcr.Status)
+
+	assert.Equal(t, heartbeat.StatusOK, hcr.Dependencies[0].Status)
+	assert.Equal(t, "slow", hcr.Dependencies[1].Name)
+	assert.Equal(t, heartbeat.StatusCritical, hcr.Dependencies[1].Status)
+	assert.Equal(t, "check timed out: health check deadline of 100ms exceeded", hcr.Dependencies[1].Message)
+}
+
+func TestDeadlineQueryParameter(t *testing.T) {
+	tests := []struct {
+		name     string
+		target   string
+		expected string
+	}{
+		{name: "duration", target: "/health?timeout=50ms", expected: "deadline of 50ms exceeded"},
+		{name: "seconds", target: "/health?timeout=0.06", expected: "deadline of 60ms exceeded"},
+		{name: "longer than configured deadline", target: "/health?timeout=1h", expected: "deadline of 100ms exceeded"},
+		{name: "invalid value falls back to configured deadline", target: "/health?timeout=soon", expected: "deadline of 100ms exceeded"},
+	}
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/health", heartbeat.NewHandler("unit-test", slowAndFastDeps(), heartbeat.WithDeadline(100*time.Millisecond)))
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			st := time.Now()
+			hcr := healthResponse(t, r, withTarget(tt.target))
+			assert.Less(t, time.Since(st), time.Second)
+			assert.Contains(t, hcr.Dependencies[1].Message, tt.expected)
+		})
+	}
+}
+
+func TestDeadlineQueryParameterWithoutConfiguredDeadline(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/health", heartbeat.Handler("unit-test", slowAndFastDeps()...))
+
+	hcr := healthResponse(t, r, withTarget("/health?timeout=100ms"))
+	assert.Equal(t, heartbeat.StatusOK, hcr.Dependencies[1].Status)
+	assert.Equal(t, "ok", hcr.Dependencies[1].Message)
+}
+
+func TestDeadlineWithCoalescing(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/health", heartbeat.NewHandler("unit-test", slowAndFastDeps(),
+		heartbeat.WithDeadline(100*time.Millisecond), heartbeat.WithCoalescing(0)))
+
+	st := time.Now()
+	hcr := healthResponse(t, r)
+	assert.Less(t, time.Since(st), time.Second)
+	require.Len(t, hcr.Dependencies, 2)
+
+	// Only the check still pending in the shared run is timed out.
+	assert.Equal(t, heartbeat.StatusOK, hcr.Dependencies[0].Status)
+	assert.Equal(t, "ok", hcr.Dependencies[0].Message)
+	assert.Equal(t, heartbeat.StatusCritical, hcr.Dependencies[1].Status)
+	assert.Equal(t, "check timed out: health check deadline of 100ms exceeded", hcr.Dependencies[1].Message)
+	assert.Equal(t, heartbeat.StatusCritical, hcr.Status)
+}
+
+func TestDeadlineCountsAsBreakerFailure(t *testing.T) {
+	hanging := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer hanging.Close()
+
+	b := heartbeat.NewCircuitBreaker(1, time.Minute)
+	deps := []heartbeat.DependencyDescriptor{
+		{Name: "hanging", Type: "HTTP", Connection: hanging.URL, Breaker: b},
+	}
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/health", heartbeat.NewHandler("unit-test", deps, heartbeat.WithDeadline(50*time.Millisecond)))
+
+	hcr := healthResponse(t, r)
+	require.Len(t, hcr.Dependencies, 1)
+	assert.Contains(t, hcr.Dependencies[0].Message, "check timed out")
+
+	// The check records its failure once it sees the deadline.
+	require.Eventually(t, func() bool { return b.State() == heartbeat.BreakerOpen }, time.Second, 5*time.Millisecond)
+
+	st := time.Now()
+	hcr = healthResponse(t, r)
+	assert.Less(t, time.Since(st), 50*time.Millisecond)
+	require.Len(t, hcr.Dependencies, 1)
+	assert.True(t, hcr.Dependencies[0].CircuitOpen)
+}
+
+func TestDeadlineQueryParameterIsNotRecorded(t *testing.T) {
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+	}))
+	defer slow.Close()
+
+	b := heartbeat.NewCircuitBreaker(1, time.Minute)
+	deps := []heartbeat.DependencyDescriptor{
+		{Name: "slow", Type: "HTTP", Connection: slow.URL, Breaker: b},
+	}
+	uptime := heartbeat.NewUptime(heartbeat.UptimeOptions{})
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/health", heartbeat.NewHandler("unit-test", deps,
+		heartbeat.WithDeadline(time.Second), heartbeat.WithUptime(uptime, false)))
+
+	for range 3 {
+		hcr := healthResponse(t, r, withTarget("/health?timeout=20ms"))
+		require.Len(t, hcr.Dependencies, 1)
+		assert.Contains(t, hcr.Dependencies[0].Message, "check timed out")
+	}
+	assert.Never(t, func() bool { return b.State() != heartbeat.BreakerClosed }, 100*time.Millisecond, 5*time.Millisecond)
+	assert.Zero(t, uptime.Report("unit-test").Service.Availability[0].Checks)
+
+	hcr := healthResponse(t, r)
+	require.Len(t, hcr.Dependencies, 1)
+	assert.Equal(t, heartbeat.StatusOK, hcr.Dependencies[0].Status)
+	assert.False(t, hcr.Dependencies[0].CircuitOpen)
+	assert.Equal(t, 1, uptime.Report("unit-test").Service.Availability[0].Checks)
+}
+
+func TestDeadlineQueryParameterDoesNotAbandonChecks(t *testing.T) {
+	deps := []heartbeat.DependencyDescriptor{
+		{Name: "slow", Type: "Custom", HandlerFunc: func() heartbeat.StatusResult {
+			time.Sleep(200 * time.Millisecond)
+			return heartbeat.StatusResult{Status: heartbeat.StatusOK, Message: "ok"}
+		}},
+	}
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/health", heartbeat.NewHandler("unit-test", deps,
+		heartbeat.WithDeadline(time.Second), heartbeat.WithMaxConcurrency(1)))
+
+	hcr := healthResponse(t, r, withTarget("/health?timeout=20ms"))
+	require.Len(t, hcr.Dependencies, 1)
+	assert.Contains(t, hcr.Dependencies[0].Message, "check timed out")
+	assert.Zero(t, hcr.AbandonedChecks)
+
+	// The next check waits for the slot of the one cut short instead of being
+	// refused while it is still running.
+	hcr = healthResponse(t, r)
+	require.Len(t, hcr.Dependencies, 1)
+	assert.Equal(t, heartbeat.StatusOK, hcr.Dependencies[0].Status)
+	assert.Equal(t, "ok", hcr.Dependencies[0].Message)
+	assert.Zero(t, hcr.AbandonedChecks)
+}
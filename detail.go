@@ -0,0 +1,224 @@
+package heartbeat
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"net/netip"
+	"slices"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DetailLevel is how much of the Response a caller may see.
+type DetailLevel int
+
+const (
+	// DetailMinimal exposes the overall status only.
+	DetailMinimal DetailLevel = iota
+	// DetailSummary adds the service name and the name and status of each
+	// dependency.
+	DetailSummary
+	// DetailFull exposes the whole Response, including dependency URLs, the
+	// machine name and error messages.
+	DetailFull
+)
+
+// String implements the Stringer interface.
+func (l DetailLevel) String() string {
+	switch l {
+	case DetailMinimal:
+		return "minimal"
+	case DetailSummary:
+		return "summary"
+	case DetailFull:
+		return "full"
+	default:
+		return fmt.Sprintf("DetailLevel(%d)", int(l))
+	}
+}
+
+// Authorizer decides the DetailLevel granted to a request.
+type Authorizer interface {
+	Authorize(c *gin.Context) DetailLevel
+}
+
+// AuthorizerFunc adapts a function to the Authorizer interface.
+type AuthorizerFunc func(c *gin.Context) DetailLevel
+
+// Authorize implements Authorizer.
+func (f AuthorizerFunc) Authorize(c *gin.Context) DetailLevel {
+	return f(c)
+}
+
+// WithAuthorizers limits the detail of the Response to the highest
+// DetailLevel granted by any of the authorizers; callers that none of them
+// recognise get DetailMinimal. Without this option every caller gets
+// DetailFull. Observers always receive the full check results.
+func WithAuthorizers(authorizers ...Authorizer) Option {
+	return func(cfg *config) {
+		for _, a := range authorizers {
+			if a != nil {
+				cfg.authorizers = append(cfg.authorizers, a)
+			}
+		}
+		if cfg.authorizers == nil {
+			cfg.authorizers = []Authorizer{}
+		}
+	}
+}
+
+// detailLevel returns the DetailLevel granted to the request.
+func (cfg *config) detailLevel(c *gin.Context) DetailLevel {
+	if cfg.authorizers == nil {
+		return DetailFull
+	}
+	level := DetailMinimal
+	for _, a := range cfg.authorizers {
+		if granted := a.Authorize(c); granted > level {
+			level = granted
+		}
+	}
+	return level
+}
+
+// RequireDetail returns a middleware that rejects requests granted less than
+// the given DetailLevel with 403 Forbidden, for protecting endpoints such as
+// the History and Uptime handlers:
+//
+//	r.GET("/health/history", heartbeat.RequireDetail(heartbeat.DetailFull, auth), history.Handler())
+func RequireDetail(level DetailLevel, authorizers ...Authorizer) gin.HandlerFunc {
+	cfg := newConfig([]Option{WithAuthorizers(authorizers...)})
+	return func(c *gin.Context) {
+		if cfg.detailLevel(c) < level {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"message": "insufficient detail level"})
+			return
+		}
+		c.Next()
+	}
+}
+
+// BearerToken grants level to requests with an "Authorization: Bearer <token>"
+// header carrying one of the tokens. Tokens are compared in constant time.
+func BearerToken(level DetailLevel, tokens ...string) Authorizer {
+	digests := make([][sha256.Size]byte, 0, len(tokens))
+	for _, t := range tokens {
+		if t != "" {
+			digests = append(digests, sha256.Sum256([]byte(t)))
+		}
+	}
+	return AuthorizerFunc(func(c *gin.Context) DetailLevel {
+		scheme, token, ok := strings.Cut(c.GetHeader("Authorization"), " ")
+		if !ok || !strings.EqualFold(scheme, "Bearer") {
+			return DetailMinimal
+		}
+		digest := sha256.Sum256([]byte(strings.TrimSpace(token)))
+		match := 0
+		for _, d := range digests {
+			match |= subtle.ConstantTimeCompare(digest[:], d[:])
+		}
+		if match == 1 {
+			return level
+		}
+		return DetailMinimal
+	})
+}
+
+// IPAllowlist grants level to requests from the given addresses or CIDR
+// prefixes, such as "10.0.0.0/8" or "::1". The client address is that of the
+// peer, gin.Context.RemoteIP, so forwarding headers can't be used to spoof an
+// allowlisted address. Behind a load balancer, use ForwardedIPAllowlist.
+func IPAllowlist(level DetailLevel, addrs ...string) (Authorizer, error) {
+	return ipAllowlist(level, (*gin.Context).RemoteIP, addrs)
+}
+
+// ForwardedIPAllowlist is like IPAllowlist, but the client address is that of
+// gin.Context.ClientIP, taken from the forwarding headers set by the engine's
+// trusted proxies. Configure them with gin.Engine.SetTrustedProxies: by
+// default gin trusts the X-Forwarded-For header of any peer.
+func ForwardedIPAllowlist(level DetailLevel, addrs ...string) (Authorizer, error) {
+	return ipAllowlist(level, (*gin.Context).ClientIP, addrs)
+}
+
+func ipAllowlist(level DetailLevel, clientIP func(*gin.Context) string, addrs []string) (Authorizer, error) {
+	prefixes := make([]netip.Prefix, 0, len(addrs))
+	for _, a := range addrs {
+		if strings.Contains(a, "/") {
+			p, err := netip.ParsePrefix(a)
+			if err != nil {
+				return nil, fmt.Errorf("invalid IP allowlist entry %q: %w", a, err)
+			}
+			prefixes = append(prefixes, p.Masked())
+			continue
+		}
+		ip, err := netip.ParseAddr(a)
+		if err != nil {
+			return nil, fmt.Errorf("invalid IP allowlist entry %q: %w", a, err)
+		}
+		prefixes = append(prefixes, netip.PrefixFrom(ip, ip.BitLen()))
+	}
+	return AuthorizerFunc(func(c *gin.Context) DetailLevel {
+		ip, err := netip.ParseAddr(clientIP(c))
+		if err != nil {
+			return DetailMinimal
+		}
+		ip = ip.Unmap()
+		if slices.ContainsFunc(prefixes, func(p netip.Prefix) bool { return p.Contains(ip) }) {
+			return level
+		}
+		return DetailMinimal
+	}), nil
+}
+
+// ClientCertificate grants level to requests whose verified TLS client
+// certificate has one of the identities as its subject common name, or as a
+// DNS or URI subject alternative name, such as a SPIFFE ID. The server must
+// verify client certificates, with tls.Config.ClientAuth set to
+// VerifyClientCertIfGiven or RequireAndVerifyClientCert.
+func ClientCertificate(level DetailLevel, identities ...string) Authorizer {
+	return AuthorizerFunc(func(c *gin.Context) DetailLevel {
+		cs := c.Request.TLS
+		if cs == nil || len(cs.VerifiedChains) == 0 || len(cs.VerifiedChains[0]) == 0 {
+			return DetailMinimal
+		}
+		cert := cs.VerifiedChains[0][0]
+		names := append([]string{cert.Subject.CommonName}, cert.DNSNames...)
+		for _, u := range cert.URIs {
+			names = append(names, u.String())
+		}
+		for _, name := range names {
+			if name != "" && slices.Contains(identities, name) {
+				return level
+			}
+		}
+		return DetailMinimal
+	})
+}
+
+// withDetail returns the Response with the fields above the level removed.
+func (h *Response) withDetail(level DetailLevel) Response {
+	switch {
+	case level >= DetailFull:
+		return *h
+	case level == DetailSummary:
+		deps := make([]StatusResult, len(h.Dependencies))
+		for i, d := range h.Dependencies {
+			deps[i] = StatusResult{Status: d.Status, Name: d.Name}
+		}
+		return Response{
+			Status:          h.Status,
+			Name:            h.Name,
+			UtcDateTime:     h.UtcDateTime,
+			RequestDuration: h.RequestDuration,
+			Dependencies:    deps,
+		}
+	default:
+		return Response{
+			Status:          h.Status,
+			UtcDateTime:     h.UtcDateTime,
+			RequestDuration: h.RequestDuration,
+		}
+	}
+}
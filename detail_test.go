@@ -0,0 +1,197 @@
+package heartbeat_test
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/twistingmercury/heartbeat"
+)
+
+// detailDeps returns a failing dependency whose result has details to hide.
+func detailDeps() []heartbeat.DependencyDescriptor {
+	return []heartbeat.DependencyDescriptor{
+		{Name: "db", Type: "database", HandlerFunc: func() heartbeat.StatusResult {
+			return heartbeat.StatusResult{Status: heartbeat.StatusCritical, Resource: "postgres://db.internal:5432", Message: "password authentication failed"}
+		}},
+	}
+}
+
+func TestDetailLevelString(t *testing.T) {
+	assert.Equal(t, "minimal", heartbeat.DetailMinimal.String())
+	assert.Equal(t, "summary", heartbeat.DetailSummary.String())
+	assert.Equal(t, "full", heartbeat.DetailFull.String())
+	assert.Equal(t, "DetailLevel(7)", heartbeat.DetailLevel(7).String())
+}
+
+func TestWithoutAuthorizersFullDetail(t *testing.T) {
+	body := healthResponse(t, healthHandler(detailDeps()))
+	require.Len(t, body.Dependencies, 1)
+	assert.Equal(t, "password authentication failed", body.Dependencies[0].Message)
+}
+
+func TestBearerTokenDetailLevels(t *testing.T) {
+	r := healthHandler(detailDeps(), heartbeat.WithAuthorizers(
+		heartbeat.BearerToken(heartbeat.DetailFull, "ops-token"),
+		heartbeat.BearerToken(heartbeat.DetailSummary, "dashboard-token"),
+	))
+
+	t.Run("anonymous", func(t *testing.T) {
+		raw := serveHealth(r, context.Background()).Body.String()
+		body := healthResponse(t, r)
+		assert.Equal(t, heartbeat.StatusCritical, body.Status)
+		assert.Empty(t, body.Name)
+		assert.Empty(t, body.Machine)
+		assert.Empty(t, body.Dependencies)
+		assert.NotContains(t, raw, "postgres")
+		assert.NotContains(t, raw, "unit-test")
+	})
+
+	t.Run("wrong token", func(t *testing.T) {
+		body := healthResponse(t, r, withHeader("Authorization", "Bearer ops-token-2"))
+		assert.Empty(t, body.Dependencies)
+	})
+
+	t.Run("summary", func(t *testing.T) {
+		summary := withHeader("Authorization", "Bearer dashboard-token")
+		raw := serveHealth(r, context.Background(), summary).Body.String()
+		body := healthResponse(t, r, summary)
+		assert.Equal(t, "unit-test", body.Name)
+		assert.Empty(t, body.Machine)
+		require.Len(t, body.Dependencies, 1)
+		assert.Equal(t, "db", body.Dependencies[0].Name)
+		assert.Equal(t, heartbeat.StatusCritical, body.Dependencies[0].Status)
+		assert.NotContains(t, raw, "postgres")
+		assert.NotContains(t, raw, "password")
+	})
+
+	t.Run("full", func(t *testing.T) {
+		body := healthResponse(t, r, withHeader("Authorization", "bearer ops-token"))
+		require.Len(t, body.Dependencies, 1)
+		assert.Equal(t, "postgres://db.internal:5432", body.Dependencies[0].Resource)
+		assert.Equal(t, "password authentication failed", body.Dependencies[0].Message)
+	})
+}
+
+func TestIPAllowlist(t *testing.T) {
+	_, err := heartbeat.IPAllowlist(heartbeat.DetailFull, "10.0.0.0/33")
+	assert.Error(t, err)
+	_, err = heartbeat.IPAllowlist(heartbeat.DetailFull, "not-an-ip")
+	assert.Error(t, err)
+
+	allow, err := heartbeat.IPAllowlist(heartbeat.DetailFull, "10.0.0.0/8", "::1")
+	require.NoError(t, err)
+	r := healthHandler(detailDeps(), heartbeat.WithAuthorizers(allow))
+
+	tests := []struct {
+		remoteAddr string
+		deps       int
+	}{
+		{remoteAddr: "10.1.2.3:4321", deps: 1},
+		{remoteAddr: "[::1]:4321", deps: 1},
+		{remoteAddr: "[::ffff:10.1.2.3]:4321", deps: 1},
+		{remoteAddr: "192.168.1.10:4321", deps: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.remoteAddr, func(t *testing.T) {
+			body := healthResponse(t, r, func(req *http.Request) { req.RemoteAddr = tt.remoteAddr })
+			assert.Len(t, body.Dependencies, tt.deps)
+		})
+	}
+
+	t.Run("forwarded address is ignored", func(t *testing.T) {
+		body := healthResponse(t, r, withHeader("X-Forwarded-For", "10.1.2.3"),
+			func(req *http.Request) { req.RemoteAddr = "192.168.1.10:4321" })
+		assert.Empty(t, body.Dependencies)
+	})
+}
+
+func TestForwardedIPAllowlist(t *testing.T) {
+	allow, err := heartbeat.ForwardedIPAllowlist(heartbeat.DetailFull, "10.0.0.0/8")
+	require.NoError(t, err)
+	r := healthHandler(detailDeps(), heartbeat.WithAuthorizers(allow))
+	require.NoError(t, r.SetTrustedProxies([]string{"192.168.1.1"}))
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		deps       int
+	}{
+		{name: "trusted proxy", remoteAddr: "192.168.1.1:4321", deps: 1},
+		{name: "untrusted peer", remoteAddr: "192.168.1.10:4321", deps: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := healthResponse(t, r, withHeader("X-Forwarded-For", "10.1.2.3"),
+				func(req *http.Request) { req.RemoteAddr = tt.remoteAddr })
+			assert.Len(t, body.Dependencies, tt.deps)
+		})
+	}
+}
+
+func TestClientCertificate(t *testing.T) {
+	r := healthHandler(detailDeps(), heartbeat.WithAuthorizers(
+		heartbeat.ClientCertificate(heartbeat.DetailFull, "spiffe://example.org/ops"),
+		heartbeat.ClientCertificate(heartbeat.DetailSummary, "monitoring"),
+	))
+	withCert := func(cert *x509.Certificate, verified bool) func(*http.Request) {
+		return func(req *http.Request) {
+			req.TLS = &tls.ConnectionState{PeerCertificates: []*x509.Certificate{cert}}
+			if verified {
+				req.TLS.VerifiedChains = [][]*x509.Certificate{{cert}}
+			}
+		}
+	}
+	ops, _ := url.Parse("spiffe://example.org/ops")
+
+	body := healthResponse(t, r, withCert(&x509.Certificate{URIs: []*url.URL{ops}}, true))
+	require.Len(t, body.Dependencies, 1)
+	assert.NotEmpty(t, body.Dependencies[0].Message)
+
+	body = healthResponse(t, r, withCert(&x509.Certificate{Subject: pkix.Name{CommonName: "monitoring"}}, true))
+	require.Len(t, body.Dependencies, 1)
+	assert.Empty(t, body.Dependencies[0].Message)
+
+	// Unverified certificates are ignored.
+	body = healthResponse(t, r, withCert(&x509.Certificate{URIs: []*url.URL{ops}}, false))
+	assert.Empty(t, body.Dependencies)
+}
+
+func TestAuthorizerFunc(t *testing.T) {
+	r := healthHandler(detailDeps(), heartbeat.WithAuthorizers(heartbeat.AuthorizerFunc(func(c *gin.Context) heartbeat.DetailLevel {
+		if c.GetHeader("X-Internal") == "yes" {
+			return heartbeat.DetailSummary
+		}
+		return heartbeat.DetailMinimal
+	})))
+
+	body := healthResponse(t, r, withHeader("X-Internal", "yes"))
+	assert.Len(t, body.Dependencies, 1)
+}
+
+func TestRequireDetail(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/health/history",
+		heartbeat.RequireDetail(heartbeat.DetailFull, heartbeat.BearerToken(heartbeat.DetailFull, "ops-token")),
+		func(c *gin.Context) { c.String(http.StatusOK, "history") })
+
+	resp := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/health/history", nil)
+	r.ServeHTTP(resp, req)
+	assert.Equal(t, http.StatusForbidden, resp.Code)
+
+	resp = httptest.NewRecorder()
+	req.Header.Set("Authorization", "Bearer ops-token")
+	r.ServeHTTP(resp, req)
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, "history", resp.Body.String())
+}
@@ -1,5 +1,7 @@
 package heartbeat
 
+import "time"
+
 // CheckDeps is exported for testing
 var CheckDeps = checkDeps
 
@@ -8,3 +10,62 @@ var CheckURL = checkURL
 
 // ExecuteHandlerWithTimeout is exported for testing
 var ExecuteHandlerWithTimeout = executeHandlerWithTimeout
+
+// DefaultTimeout is exported for testing
+const DefaultTimeout = defaultTimeout
+
+// DefaultScriptTimeout is exported for testing
+const DefaultScriptTimeout = defaultScriptTimeout
+
+// ParsePluginOutput is exported for testing
+var ParsePluginOutput = parsePluginOutput
+
+// SetClock replaces the clock used by the passive checks, for testing
+func (p *PassiveChecks) SetClock(now func() time.Time) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.now = now
+}
+
+// SetTimeout sets the timeout of the check that produced the result, for testing
+func (hsr *StatusResult) SetTimeout(timeout time.Duration) {
+	hsr.timeout = timeout
+}
+
+// CheckDependency is exported for testing
+var CheckDependency = checkDependency
+
+// SetClock replaces the clock used by the circuit breaker, for testing
+func (b *CircuitBreaker) SetClock(now func() time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.now = now
+}
+
+// SetClock replaces the clock used to resolve relative times, for testing
+func (h *History) SetClock(now func() time.Time) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.now = now
+}
+
+// SetClock replaces the clock used to compute the rolling windows, for testing
+func (u *Uptime) SetClock(now func() time.Time) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.now = now
+}
+
+// SetClock replaces the clock used to apply the retention, for testing
+func (fs *FileStore) SetClock(now func() time.Time) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	fs.now = now
+}
+
+// SetClock replaces the clock used to expire cached tokens, for testing
+func (o *OAuth2ClientCredentials) SetClock(now func() time.Time) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.now = now
+}
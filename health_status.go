@@ -8,6 +8,30 @@ NotSet
 OK
 Warning
 Critical
+Unknown
 )
 */
 type Status int
+
+// Severity ranks the status from healthy to unhealthy: NotSet, OK, Warning,
+// Unknown, Critical. Unknown was added after the numeric values of the others
+// were released, so compare severities rather than Status values.
+func (x Status) Severity() int {
+	switch x {
+	case StatusOK:
+		return 1
+	case StatusWarning:
+		return 2
+	case StatusUnknown:
+		return 3
+	case StatusCritical:
+		return 4
+	default:
+		return 0
+	}
+}
+
+// failing reports whether the status is Unknown or Critical.
+func (x Status) failing() bool {
+	return x.Severity() >= StatusUnknown.Severity()
+}
@@ -19,15 +19,18 @@ const (
 	StatusWarning
 	// StatusCritical is a Status of type Critical.
 	StatusCritical
+	// StatusUnknown is a Status of type Unknown.
+	StatusUnknown
 )
 
-const _StatusName = "NotSetOKWarningCritical"
+const _StatusName = "NotSetOKWarningCriticalUnknown"
 
 var _StatusMap = map[Status]string{
 	StatusNotSet:   _StatusName[0:6],
 	StatusOK:       _StatusName[6:8],
 	StatusWarning:  _StatusName[8:15],
 	StatusCritical: _StatusName[15:23],
+	StatusUnknown:  _StatusName[23:30],
 }
 
 // String implements the Stringer interface.
@@ -43,6 +46,7 @@ var _StatusValue = map[string]Status{
 	_StatusName[6:8]:   StatusOK,
 	_StatusName[8:15]:  StatusWarning,
 	_StatusName[15:23]: StatusCritical,
+	_StatusName[23:30]: StatusUnknown,
 }
 
 // ParseStatus attempts to convert a string to a Status.
@@ -0,0 +1,126 @@
+package heartbeat
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// HealthJSONContentType is the media type of the IETF Health Check Response
+// Format for HTTP APIs.
+const HealthJSONContentType = "application/health+json"
+
+// Health check response statuses of the IETF format.
+const (
+	HealthPass = "pass"
+	HealthWarn = "warn"
+	HealthFail = "fail"
+)
+
+// HealthJSON is a Response in the IETF Health Check Response Format for HTTP
+// APIs (draft-inadarei-api-health-check).
+type HealthJSON struct {
+	Status    string                       `json:"status"`
+	ServiceID string                       `json:"serviceId,omitempty"`
+	Output    string                       `json:"output,omitempty"`
+	Checks    map[string][]HealthJSONCheck `json:"checks,omitempty"`
+}
+
+// HealthJSONCheck is a measurement of a dependency in the "checks" object,
+// keyed by "<dependency name>:<measurement>".
+type HealthJSONCheck struct {
+	ComponentType string    `json:"componentType,omitempty"`
+	ObservedValue any       `json:"observedValue"`
+	ObservedUnit  string    `json:"observedUnit,omitempty"`
+	Status        string    `json:"status"`
+	Time          time.Time `json:"time"`
+	Output        string    `json:"output,omitempty"`
+}
+
+// RenderHealthJSON renders the Response in the IETF Health Check Response
+// Format, with the application/health+json media type.
+func RenderHealthJSON(c *gin.Context, httpStatus int, resp Response) {
+	body, err := json.Marshal(resp.HealthJSON())
+	if err != nil {
+		_ = c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+	c.Data(httpStatus, HealthJSONContentType, body)
+}
+
+// HealthJSON converts the Response to the IETF Health Check Response Format.
+// Every dependency has a "<name>:responseTime" check in milliseconds, and a
+// "<name>:<label>" check for each of its Metrics. OK maps to pass, Warning to
+// warn, and Critical and Unknown to fail.
+func (h *Response) HealthJSON() HealthJSON {
+	hj := HealthJSON{
+		Status:    healthJSONStatus(h.Status),
+		ServiceID: h.Name,
+		Output:    h.Message,
+	}
+	if hj.Status != HealthPass && hj.Output == "" {
+		hj.Output = healthJSONOutput(h.Dependencies)
+	}
+	if len(h.Dependencies) == 0 {
+		return hj
+	}
+
+	hj.Checks = make(map[string][]HealthJSONCheck)
+	for _, dep := range h.Dependencies {
+		status := healthJSONStatus(dep.Status)
+		check := HealthJSONCheck{
+			ComponentType: dep.Type,
+			ObservedValue: dep.RequestDuration,
+			ObservedUnit:  "ms",
+			Status:        status,
+			Time:          h.UtcDateTime,
+		}
+		if status != HealthPass {
+			check.Output = dep.Message
+		}
+		key := dep.Name + ":responseTime"
+		hj.Checks[key] = append(hj.Checks[key], check)
+
+		for _, m := range dep.Metrics {
+			key := dep.Name + ":" + m.Label
+			hj.Checks[key] = append(hj.Checks[key], HealthJSONCheck{
+				ComponentType: dep.Type,
+				ObservedValue: m.Value,
+				ObservedUnit:  m.Unit,
+				Status:        status,
+				Time:          h.UtcDateTime,
+			})
+		}
+	}
+	return hj
+}
+
+func healthJSONStatus(s Status) string {
+	switch s {
+	case StatusWarning:
+		return HealthWarn
+	case StatusCritical, StatusUnknown:
+		return HealthFail
+	default:
+		return HealthPass
+	}
+}
+
+// healthJSONOutput lists the dependencies that are not OK, with their messages.
+func healthJSONOutput(deps []StatusResult) string {
+	var failing []string
+	for _, dep := range deps {
+		if dep.Status.Severity() <= StatusOK.Severity() {
+			continue
+		}
+		out := dep.Name + " is " + dep.Status.String()
+		if msg := firstLine(dep.Message); msg != "" {
+			out += ": " + msg
+		}
+		failing = append(failing, out)
+	}
+	return strings.Join(failing, "; ")
+}
@@ -0,0 +1,101 @@
+package heartbeat_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/twistingmercury/heartbeat"
+)
+
+func TestResponseHealthJSON(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	resp := heartbeat.Response{
+		Status:      heartbeat.StatusCritical,
+		Name:        "orders",
+		UtcDateTime: now,
+		Dependencies: []heartbeat.StatusResult{
+			{Status: heartbeat.StatusOK, Name: "payments", Type: "HTTP", RequestDuration: 12.5, Message: "ok"},
+			{Status: heartbeat.StatusWarning, Name: "cache", Type: "redis", RequestDuration: 3, Message: "slow"},
+			{Status: heartbeat.StatusCritical, Name: "db", Type: "datastore", RequestDuration: 3, Message: "connection refused\nmore detail",
+				Metrics: []heartbeat.Metric{{Label: "conns", Value: 7, Unit: ""}}},
+		},
+	}
+
+	hj := resp.HealthJSON()
+	assert.Equal(t, heartbeat.HealthFail, hj.Status)
+	assert.Equal(t, "orders", hj.ServiceID)
+	assert.Equal(t, "cache is Warning: slow; db is Critical: connection refused", hj.Output)
+	require.Len(t, hj.Checks, 4)
+
+	assert.Equal(t, []heartbeat.HealthJSONCheck{{
+		ComponentType: "HTTP",
+		ObservedValue: 12.5,
+		ObservedUnit:  "ms",
+		Status:        heartbeat.HealthPass,
+		Time:          now,
+	}}, hj.Checks["payments:responseTime"])
+	assert.Equal(t, heartbeat.HealthWarn, hj.Checks["cache:responseTime"][0].Status)
+	assert.Equal(t, "slow", hj.Checks["cache:responseTime"][0].Output)
+
+	db := hj.Checks["db:responseTime"][0]
+	assert.Equal(t, heartbeat.HealthFail, db.Status)
+	assert.Equal(t, "datastore", db.ComponentType)
+
+	conns := hj.Checks["db:conns"][0]
+	assert.Equal(t, float64(7), conns.ObservedValue)
+	assert.Equal(t, heartbeat.HealthFail, conns.Status)
+}
+
+func TestResponseHealthJSONStatuses(t *testing.T) {
+	for status, want := range map[heartbeat.Status]string{
+		heartbeat.StatusNotSet:   heartbeat.HealthPass,
+		heartbeat.StatusOK:       heartbeat.HealthPass,
+		heartbeat.StatusWarning:  heartbeat.HealthWarn,
+		heartbeat.StatusUnknown:  heartbeat.HealthFail,
+		heartbeat.StatusCritical: heartbeat.HealthFail,
+	} {
+		resp := heartbeat.Response{Status: status, Name: "orders"}
+		hj := resp.HealthJSON()
+		assert.Equal(t, want, hj.Status, status.String())
+		assert.Nil(t, hj.Checks)
+	}
+}
+
+func TestHandlerRenderHealthJSON(t *testing.T) {
+	deps := []heartbeat.DependencyDescriptor{
+		{Name: "custom", Type: "component", HandlerFunc: func() heartbeat.StatusResult {
+			return heartbeat.StatusResult{Status: heartbeat.StatusCritical, Message: "down"}
+		}},
+	}
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/health", heartbeat.NewHandler("unit-test", deps, heartbeat.WithRenderer(heartbeat.RenderHealthJSON)))
+
+	resp := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/health", nil)
+	r.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusServiceUnavailable, resp.Code)
+	assert.Equal(t, heartbeat.HealthJSONContentType, resp.Header().Get("Content-Type"))
+
+	var body map[string]any
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &body))
+	assert.Equal(t, "fail", body["status"])
+	assert.Equal(t, "unit-test", body["serviceId"])
+	assert.Equal(t, "custom is Critical: down", body["output"])
+	assert.NotContains(t, body, "utc_DateTime")
+
+	checks := body["checks"].(map[string]any)
+	check := checks["custom:responseTime"].([]any)[0].(map[string]any)
+	assert.Equal(t, "component", check["componentType"])
+	assert.Equal(t, "ms", check["observedUnit"])
+	assert.Equal(t, "fail", check["status"])
+	assert.Contains(t, check, "time")
+}
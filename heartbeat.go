@@ -3,7 +3,9 @@ package heartbeat
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"net"
 	"net/http"
 	"net/url"
 	"os"
@@ -11,6 +13,15 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// defaultTimeout applies to dependency checks that don't set a Timeout.
+	defaultTimeout = 10 * time.Second
+
+	// slowResponseThreshold is the response time above which an HTTP dependency is reported as Warning.
+	slowResponseThreshold = 3 * time.Second
 )
 
 // StatusHandlerFunc is a function that returns the status of a resource.
@@ -23,6 +34,16 @@ type DependencyDescriptor struct {
 	Connection  string            `json:"connection"`
 	HandlerFunc StatusHandlerFunc `json:"-"`
 	Timeout     time.Duration     `json:"timeout,omitempty"`
+	Retry       *RetryPolicy      `json:"retry,omitempty"`
+	Breaker     *CircuitBreaker   `json:"-"`
+	Hysteresis  *Hysteresis       `json:"-"`
+
+	// Secrets are the credentials referenced by ${name} placeholders in
+	// Connection, resolved each time the dependency is checked.
+	Secrets map[string]Secret `json:"-"`
+
+	// Auth authenticates the requests of HTTP checks.
+	Auth HTTPAuth `json:"-"`
 }
 
 func (d *DependencyDescriptor) String() string {
@@ -32,12 +53,24 @@ func (d *DependencyDescriptor) String() string {
 
 // StatusResult represents another process or API that this service relies upon to be considered healthy.
 type StatusResult struct {
-	Status          Status  `json:"status"`
-	Name            string  `json:"name,omitempty"`
-	Resource        string  `json:"resource"`
-	RequestDuration float64 `json:"request_duration_ms"`
-	StatusCode      int     `json:"http_status_code"`
-	Message         string  `json:"message,omitempty"`
+	Status          Status   `json:"status"`
+	RawStatus       Status   `json:"raw_status,omitempty"`
+	Name            string   `json:"name,omitempty"`
+	Type            string   `json:"type,omitempty"`
+	Resource        string   `json:"resource"`
+	RequestDuration float64  `json:"request_duration_ms"`
+	StatusCode      int      `json:"http_status_code"`
+	Message         string   `json:"message,omitempty"`
+	Attempts        int      `json:"attempts,omitempty"`
+	CircuitOpen     bool     `json:"circuit_open,omitempty"`
+	AuthFailed      bool     `json:"auth_failed,omitempty"`
+	Metrics         []Metric `json:"metrics,omitempty"`
+
+	History *HistorySummary `json:"history,omitempty"`
+
+	// timeout is the time the check was allowed, the critical threshold of
+	// its Nagios perfdata.
+	timeout time.Duration
 }
 
 func (dep *StatusResult) String() string {
@@ -54,6 +87,8 @@ type Response struct {
 	UtcDateTime     time.Time      `json:"utc_DateTime"`
 	RequestDuration float64        `json:"request_duration_ms"`
 	Message         string         `json:"message,omitempty"`
+	AbandonedChecks int            `json:"abandoned_checks,omitempty"`
+	Uptime          *UptimeReport  `json:"uptime,omitempty"`
 	Dependencies    []StatusResult `json:"dependencies,omitempty"`
 }
 
@@ -64,95 +99,325 @@ func (h *Response) String() string {
 
 // Handler returns the health of the app as a Response object.
 func Handler(svcName string, deps ...DependencyDescriptor) gin.HandlerFunc {
-	return func(c *gin.Context) {
-		st := time.Now()
+	return NewHandler(svcName, deps)
+}
 
-		// Get hostname; use empty string as fallback if unavailable
-		hostname, err := os.Hostname()
-		if err != nil {
-			hostname = ""
-		}
+// NewHandler returns the health of the app as a Response object, configured by
+// the given options.
+func NewHandler(svcName string, deps []DependencyDescriptor, opts ...Option) gin.HandlerFunc {
+	return newHandler(svcName, deps, opts).serve
+}
 
-		hb := Response{
-			Name:        svcName,
-			Resource:    svcName,
-			Machine:     hostname,
-			UtcDateTime: time.Now().UTC(),
-		}
+func newHandler(svcName string, deps []DependencyDescriptor, opts []Option) *handler {
+	h := &handler{
+		name:   svcName,
+		deps:   deps,
+		cfg:    newConfig(opts),
+		runner: &runner{},
+	}
+	h.runner.tracer = h.cfg.tracer
+	h.runner.redactor = h.cfg.redactor
+	if h.cfg.maxConcurrency != 0 {
+		h.runner.bulkhead = newBulkhead(h.cfg.maxConcurrency)
+	}
+	if h.cfg.coalesce {
+		h.flight = &coalescer{reuse: h.cfg.coalesceReuse}
+	}
+	return h
+}
+
+// handler holds the state of a health check endpoint across requests.
+type handler struct {
+	name   string
+	deps   []DependencyDescriptor
+	cfg    *config
+	runner *runner
+	flight *coalescer
+}
 
-		// Get context from request for cancellation and deadline propagation
-		ctx := c.Request.Context()
-		status, checkedDeps := checkDeps(ctx, deps)
-		hb.Dependencies = checkedDeps
-		hb.Status = status
-
-		hb.RequestDuration = float64(time.Since(st).Microseconds()) / 1000
-
-		httpStatus := http.StatusOK
-		switch hb.Status {
-		case StatusCritical:
-			httpStatus = http.StatusServiceUnavailable // 503
-		case StatusWarning:
-			httpStatus = http.StatusOK // 200 - still operational but degraded
-		case StatusOK, StatusNotSet:
-			httpStatus = http.StatusOK // 200 - healthy or no dependencies checked
+func (h *handler) serve(c *gin.Context) {
+	st := time.Now()
+
+	render, err := h.cfg.negotiate(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+	detail := h.cfg.detailLevel(c)
+
+	// Get hostname; use empty string as fallback if unavailable
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = ""
+	}
+
+	hb := Response{
+		Name:        h.name,
+		Resource:    h.name,
+		Machine:     hostname,
+		UtcDateTime: time.Now().UTC(),
+	}
+
+	// Get context from request for cancellation and deadline propagation
+	ctx, endSpan := h.startHealthSpan(c.Request.Context())
+	ctx, cancel := withDeadline(ctx, requestDeadline(c.Query(TimeoutQueryParam), h.cfg.deadline))
+	defer cancel()
+	status, checkedDeps := h.check(ctx)
+	hb.Dependencies = checkedDeps
+	hb.Status = status
+	if h.cfg.history != nil {
+		for i := range hb.Dependencies {
+			hb.Dependencies[i].History = h.cfg.history.Summary(hb.Dependencies[i].Name, h.cfg.historySummary)
 		}
-		c.JSON(httpStatus, hb)
 	}
+	if h.cfg.uptime != nil {
+		report := h.cfg.uptime.Report(h.name)
+		hb.Uptime = &report
+	}
+	if h.runner.bulkhead != nil {
+		hb.AbandonedChecks = h.runner.bulkhead.Abandoned()
+	}
+
+	hb.RequestDuration = float64(time.Since(st).Microseconds()) / 1000
+
+	httpStatus := h.cfg.statusCodes.code(hb.Status)
+	h.cfg.writeHeaders(c.Writer.Header(), hb.Status, httpStatus)
+	endSpan(hb.Status, httpStatus)
+	render(c, httpStatus, hb.withDetail(detail))
+}
+
+// check runs the dependency checks, sharing a single run between concurrent
+// requests when coalescing is enabled.
+func (h *handler) check(ctx context.Context) (Status, []StatusResult) {
+	if h.flight == nil {
+		return h.run(ctx, h.deps, nil)
+	}
+	status, results := h.flight.do(ctx, h.deps, h.run)
+	// Results made up by the coalescer, such as those of checks pending at
+	// the deadline, haven't been redacted by the runner.
+	for i := range results {
+		results[i] = h.runner.redact(results[i])
+	}
+	return status, results
+}
+
+// run executes the dependency checks and hands the outcome to the observers.
+func (h *handler) run(ctx context.Context, deps []DependencyDescriptor, onResult func(int, StatusResult)) (Status, []StatusResult) {
+	st := time.Now()
+	status, results := h.runner.checkDeps(ctx, deps, onResult)
+	if len(h.cfg.observers) == 0 || callerGone(ctx) {
+		return status, results
+	}
+
+	run := CheckRun{
+		Service:  h.name,
+		Status:   status,
+		Time:     time.Now(),
+		Duration: time.Since(st),
+		Results:  results,
+	}
+	for _, o := range h.cfg.observers {
+		o.ObserveRun(run)
+	}
+	return status, results
 }
 
+// runner executes dependency checks within the execution limits of a handler.
+type runner struct {
+	bulkhead *bulkhead
+	tracer   trace.Tracer // nil when the handler is not traced
+	redactor *Redactor    // nil when results are not redacted
+}
+
+// defaultRunner runs checks without execution limits.
+var defaultRunner = &runner{}
+
 func checkDeps(ctx context.Context, deps []DependencyDescriptor) (status Status, hbl []StatusResult) {
+	return defaultRunner.checkDeps(ctx, deps, nil)
+}
+
+// checkDeps checks the dependencies concurrently, passing each result to
+// onResult, when set, as soon as it is known.
+func (rn *runner) checkDeps(ctx context.Context, deps []DependencyDescriptor, onResult func(int, StatusResult)) (status Status, hbl []StatusResult) {
+	st := time.Now()
+
 	// Pre-allocate results slice with known length
 	results := make([]StatusResult, len(deps))
+	completed := make([]bool, len(deps))
 
 	// Use WaitGroup for concurrent dependency checking
 	var wg sync.WaitGroup
 	var mu sync.Mutex // Protect status variable
+	expired := false  // Set once the handler's deadline has passed
 
 	for i, desc := range deps {
 		wg.Add(1)
 		go func(index int, d DependencyDescriptor) {
 			defer wg.Done()
 
-			var hsr StatusResult
-
-			switch {
-			case d.HandlerFunc != nil:
-				// Wrap custom handler with timeout enforcement
-				hsr = executeHandlerWithTimeout(ctx, d.HandlerFunc, d.Timeout)
-			default:
-				hsr = checkURL(ctx, d.Connection, d.Timeout)
+			spanCtx, endSpan := rn.startCheckSpan(ctx, d)
+			hsr := rn.checkDependency(spanCtx, d)
+			var de deadlineExceeded
+			if ctx.Err() != nil && errors.As(context.Cause(ctx), &de) {
+				// Cut short by the handler's deadline
+				hsr = timedOutResult(d, de, time.Since(st))
 			}
 
-			// Set name from descriptor
+			// Set name, type and timeout from descriptor
 			hsr.Name = d.Name
+			hsr.Type = d.Type
+			hsr.timeout = checkTimeout(d)
 
 			// Fix Issue #1: Set Resource field for custom handlers if empty
 			if hsr.Resource == "" {
 				hsr.Resource = d.Name
 			}
+			hsr = rn.redact(hsr)
+			endSpan(hsr)
 
 			// Thread-safe status update
 			mu.Lock()
-			if hsr.Status > status {
+			defer mu.Unlock()
+			if expired {
+				// Already reported as timed out
+				return
+			}
+			if hsr.Status.Severity() > status.Severity() {
 				status = hsr.Status
 			}
 			results[index] = hsr
-			mu.Unlock()
+			completed[index] = true
+			if onResult != nil {
+				onResult(index, hsr)
+			}
 		}(i, desc)
 	}
 
-	wg.Wait()
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+		var de deadlineExceeded
+		if !errors.As(context.Cause(ctx), &de) {
+			// Cancelled by the caller; the checks see it and return promptly.
+			<-done
+			break
+		}
+
+		// Don't wait past the handler's deadline for checks still pending.
+		mu.Lock()
+		expired = true
+		for i, d := range deps {
+			if completed[i] {
+				continue
+			}
+			results[i] = rn.redact(timedOutResult(d, de, time.Since(st)))
+			status = StatusCritical
+		}
+		mu.Unlock()
+	}
 	return status, results
 }
 
+// redact removes credentials from the result when the runner has a redactor.
+func (rn *runner) redact(hsr StatusResult) StatusResult {
+	if rn.redactor == nil {
+		return hsr
+	}
+	return rn.redactor.RedactResult(hsr)
+}
+
+// checkDependency runs the check for a single dependency, retrying failed
+// attempts when the descriptor has a retry policy, short-circuiting it while
+// the descriptor's circuit breaker is open and damping its status through the
+// descriptor's hysteresis.
+func checkDependency(ctx context.Context, d DependencyDescriptor) StatusResult {
+	return defaultRunner.checkDependency(ctx, d)
+}
+
+func (rn *runner) checkDependency(ctx context.Context, d DependencyDescriptor) StatusResult {
+	check := func() StatusResult {
+		if d.Retry != nil && d.Retry.Attempts > 1 {
+			return rn.checkWithRetry(ctx, d)
+		}
+		hsr, _ := rn.attemptCheck(ctx, d, d.Timeout)
+		return hsr
+	}
+
+	if d.Breaker != nil {
+		retrying := check
+		check = func() StatusResult {
+			hsr := checkWithBreaker(ctx, d, retrying)
+			if hsr.CircuitOpen && d.HandlerFunc == nil {
+				hsr.Resource = d.Connection
+			}
+			return hsr
+		}
+	}
+
+	if d.Hysteresis != nil {
+		return checkWithHysteresis(ctx, d, check)
+	}
+	return check()
+}
+
+// attemptCheck makes a single attempt at checking the dependency.
+func (rn *runner) attemptCheck(ctx context.Context, d DependencyDescriptor, timeout time.Duration) (StatusResult, RetryOn) {
+	// Default timeout for custom handlers
+	if timeout == 0 {
+		timeout = defaultTimeout
+	}
+
+	slot, denied := rn.bulkhead.acquire(ctx, d, timeout)
+	if denied != nil {
+		return *denied, 0
+	}
+
+	if d.HandlerFunc != nil {
+		// Wrap custom handler with timeout enforcement; an abandoned handler
+		// keeps its slot until it finally returns.
+		hsr, abandoned := runHandler(ctx, d.HandlerFunc, timeout, slot)
+		if !abandoned {
+			slot.release()
+		}
+		if hsr.Status.failing() {
+			return hsr, RetryHandlerFailures
+		}
+		return hsr, 0
+	}
+
+	defer slot.release()
+	return probeSecretURL(ctx, d, timeout)
+}
+
+// checkTimeout returns the time a check of the dependency is allowed.
+func checkTimeout(d DependencyDescriptor) time.Duration {
+	if d.Timeout == 0 {
+		return defaultTimeout
+	}
+	return d.Timeout
+}
+
 // executeHandlerWithTimeout wraps custom handler execution with timeout enforcement
 func executeHandlerWithTimeout(ctx context.Context, handler StatusHandlerFunc, timeout time.Duration) StatusResult {
 	// Default timeout for custom handlers
 	if timeout == 0 {
-		timeout = 10 * time.Second
+		timeout = defaultTimeout
 	}
+	hsr, _ := runHandler(ctx, handler, timeout, nil)
+	return hsr
+}
 
+// runHandler executes the handler with timeout enforcement and reports whether
+// it was abandoned, still running, when the timeout expired or the caller
+// stopped waiting. The slot, if any, is told when the handler is abandoned or
+// left and when such a handler returns.
+func runHandler(ctx context.Context, handler StatusHandlerFunc, timeout time.Duration, slot *slot) (StatusResult, bool) {
 	// Create timeout context
 	timeoutCtx, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
@@ -162,6 +427,7 @@ func executeHandlerWithTimeout(ctx context.Context, handler StatusHandlerFunc, t
 
 	// Execute handler in goroutine with panic recovery
 	go func() {
+		defer slot.exit()
 		defer func() {
 			if r := recover(); r != nil {
 				// Panic occurred in handler - convert to critical status result
@@ -188,18 +454,43 @@ func executeHandlerWithTimeout(ctx context.Context, handler StatusHandlerFunc, t
 	// Wait for result or timeout
 	select {
 	case result := <-resultChan:
-		return result
+		return result, false
 	case <-timeoutCtx.Done():
 		// Timeout or cancellation occurred
-		return StatusResult{
+		hsr := StatusResult{
 			Status:  StatusCritical,
 			Message: fmt.Sprintf("custom handler timeout after %v", timeout),
 		}
+		if callerGone(ctx) {
+			// The handler isn't stuck; the caller stopped waiting for it
+			return hsr, slot.leave()
+		}
+		return hsr, slot.abandon()
 	}
 }
 
 func checkURL(ctx context.Context, urlStr string, timeout time.Duration) StatusResult {
-	var hsr StatusResult
+	hsr, _ := probeURL(ctx, urlStr, timeout, nil)
+	return hsr
+}
+
+// probeURL checks the URL, authenticating the request with auth when it is
+// set, and classifies any failure so that it can be retried.
+func probeURL(ctx context.Context, urlStr string, timeout time.Duration, auth HTTPAuth) (StatusResult, RetryOn) {
+	// Set timeout with default
+	if timeout == 0 {
+		timeout = defaultTimeout
+	}
+
+	// Bound obtaining credentials and the request together by the timeout
+	reqCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	return probeURLWithin(ctx, reqCtx, urlStr, auth)
+}
+
+// probeURLWithin checks the URL like probeURL, within reqCtx, the context
+// bounded by the check's timeout that is derived from ctx.
+func probeURLWithin(ctx, reqCtx context.Context, urlStr string, auth HTTPAuth) (hsr StatusResult, failure RetryOn) {
 	st := time.Now()
 
 	// Validate URL
@@ -209,7 +500,7 @@ func checkURL(ctx context.Context, urlStr string, timeout time.Duration) StatusR
 		hsr.Message = fmt.Sprintf("invalid URL: %v", err)
 		hsr.Resource = urlStr
 		hsr.Name = urlStr
-		return hsr
+		return hsr, 0
 	}
 
 	// Only allow HTTP and HTTPS schemes
@@ -218,32 +509,28 @@ func checkURL(ctx context.Context, urlStr string, timeout time.Duration) StatusR
 		hsr.Message = fmt.Sprintf("unsupported URL scheme: %s (only http/https allowed)", parsedURL.Scheme)
 		hsr.Resource = urlStr
 		hsr.Name = urlStr
-		return hsr
+		return hsr, 0
 	}
 
 	hsr.Name = urlStr
 	hsr.Resource = urlStr
 	hsr.Status = StatusNotSet
 
-	// Set timeout with default
-	if timeout == 0 {
-		timeout = 10 * time.Second
-	}
-
-	// Create HTTP client with timeout
-	client := &http.Client{
-		Timeout: timeout,
-	}
-
 	// Create request with context for cancellation support
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, urlStr, nil)
+	req, err := http.NewRequestWithContext(reqCtx, http.MethodGet, urlStr, nil)
 	if err != nil {
 		hsr.Status = StatusCritical
 		hsr.Message = fmt.Sprintf("failed to create request: %v", err)
-		return hsr
+		return hsr, 0
+	}
+
+	if !authenticate(reqCtx, auth, req, &hsr) {
+		hsr.RequestDuration = float64(time.Since(st).Microseconds()) / 1000
+		return hsr, RetryAuthFailures
 	}
 
 	// Make HTTP request
+	client := &http.Client{}
 	r, err := client.Do(req)
 	elapsed := time.Since(st)
 	hsr.RequestDuration = float64(elapsed.Microseconds()) / 1000
@@ -251,32 +538,44 @@ func checkURL(ctx context.Context, urlStr string, timeout time.Duration) StatusR
 	if err != nil {
 		hsr.Status = StatusCritical
 		// Check if error is due to context cancellation
-		if ctx.Err() != nil {
+		var netErr net.Error
+		switch {
+		case ctx.Err() != nil:
 			hsr.Message = fmt.Sprintf("request cancelled: %v", ctx.Err())
-		} else {
+		case errors.As(err, &netErr) && netErr.Timeout():
+			hsr.Message = fmt.Sprintf("HTTP request failed: %v", err)
+			failure = RetryTimeouts
+		default:
 			hsr.Message = fmt.Sprintf("HTTP request failed: %v", err)
+			failure = RetryConnectErrors
 		}
-		return hsr
+		return hsr, failure
 	}
 
 	defer func() {
 		_ = r.Body.Close() // Error intentionally ignored - cleanup operation after successful request
 	}()
 	hsr.StatusCode = r.StatusCode
+	if inv, ok := auth.(invalidator); ok && r.StatusCode == http.StatusUnauthorized {
+		// Fetch fresh credentials for the next check
+		inv.Invalidate()
+	}
 
 	// Evaluate status based on HTTP status code and response time
 	switch {
 	case r.StatusCode >= 500:
 		hsr.Status = StatusCritical
 		hsr.Message = fmt.Sprintf("server error (HTTP %d)", r.StatusCode)
+		failure = RetryServerErrors
 	case r.StatusCode >= 400:
 		hsr.Status = StatusCritical
 		hsr.Message = fmt.Sprintf("client error (HTTP %d)", r.StatusCode)
+		failure = RetryClientErrors
 	case r.StatusCode >= 300:
 		hsr.Status = StatusWarning
 		hsr.Message = fmt.Sprintf("redirect (HTTP %d)", r.StatusCode)
 	case r.StatusCode >= 200:
-		if elapsed > 3*time.Second {
+		if elapsed > slowResponseThreshold {
 			hsr.Status = StatusWarning
 			hsr.Message = fmt.Sprintf("slow response (%v)", elapsed)
 		} else {
@@ -288,5 +587,5 @@ func checkURL(ctx context.Context, urlStr string, timeout time.Duration) StatusR
 		hsr.Message = fmt.Sprintf("unexpected status (HTTP %d)", r.StatusCode)
 	}
 
-	return hsr
+	return hsr, failure
 }
@@ -143,9 +143,37 @@ func TestStatusString(t *testing.T) {
 	assert.Equal(t, "OK", heartbeat.StatusOK.String())
 	assert.Equal(t, "Warning", heartbeat.StatusWarning.String())
 	assert.Equal(t, "Critical", heartbeat.StatusCritical.String())
+	assert.Equal(t, "Unknown", heartbeat.StatusUnknown.String())
 	assert.Equal(t, "Status(5)", heartbeat.Status(5).String())
 }
 
+func TestStatusValuesAreStable(t *testing.T) {
+	assert.Equal(t, 0, int(heartbeat.StatusNotSet))
+	assert.Equal(t, 1, int(heartbeat.StatusOK))
+	assert.Equal(t, 2, int(heartbeat.StatusWarning))
+	assert.Equal(t, 3, int(heartbeat.StatusCritical))
+	assert.Equal(t, 4, int(heartbeat.StatusUnknown))
+}
+
+func TestStatusSeverity(t *testing.T) {
+	ranked := []heartbeat.Status{heartbeat.StatusNotSet, heartbeat.StatusOK, heartbeat.StatusWarning, heartbeat.StatusUnknown, heartbeat.StatusCritical}
+	for i := 1; i < len(ranked); i++ {
+		assert.Less(t, ranked[i-1].Severity(), ranked[i].Severity(), "%s must rank below %s", ranked[i-1], ranked[i])
+	}
+}
+
+func TestUnknownRanksBelowCritical(t *testing.T) {
+	status, _ := heartbeat.CheckDeps(context.Background(), []heartbeat.DependencyDescriptor{
+		{Name: "critical", HandlerFunc: func() heartbeat.StatusResult {
+			return heartbeat.StatusResult{Status: heartbeat.StatusCritical}
+		}},
+		{Name: "unknown", HandlerFunc: func() heartbeat.StatusResult {
+			return heartbeat.StatusResult{Status: heartbeat.StatusUnknown}
+		}},
+	})
+	assert.Equal(t, heartbeat.StatusCritical, status)
+}
+
 func TestStatusParse(t *testing.T) {
 	ok, err := heartbeat.ParseStatus("OK")
 	assert.NoError(t, err)
@@ -1610,7 +1638,7 @@ func TestCheckDepsRace(t *testing.T) {
 				// Verify that the final status matches the maximum status in results
 				maxStatus := heartbeat.StatusNotSet
 				for _, result := range results {
-					if result.Status > maxStatus {
+					if result.Status.Severity() > maxStatus.Severity() {
 						maxStatus = result.Status
 					}
 				}
@@ -1809,3 +1837,23 @@ func TestHandlerNameField(t *testing.T) {
 		})
 	}
 }
+
+func TestHandlerReturnsServiceUnavailableForUnknown(t *testing.T) {
+	deps := []heartbeat.DependencyDescriptor{
+		{Name: "script", Type: "Custom", HandlerFunc: func() heartbeat.StatusResult {
+			return heartbeat.StatusResult{Status: heartbeat.StatusUnknown}
+		}},
+	}
+
+	resp := httptest.NewRecorder()
+	gin.SetMode(gin.TestMode)
+	c, r := gin.CreateTestContext(resp)
+	r.GET("/test", heartbeat.Handler("unit-test", deps...))
+	c.Request, _ = http.NewRequest(http.MethodGet, "/test", nil)
+	r.ServeHTTP(resp, c.Request)
+
+	var hcr heartbeat.Response
+	assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), &hcr))
+	assert.Equal(t, heartbeat.StatusUnknown, hcr.Status)
+	assert.Equal(t, http.StatusServiceUnavailable, resp.Code)
+}
@@ -0,0 +1,244 @@
+package heartbeat
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// HistoryEntry is the result of one check of a dependency.
+type HistoryEntry struct {
+	Time     time.Time `json:"time"`
+	Status   Status    `json:"status"`
+	Duration float64   `json:"duration_ms"`
+	Message  string    `json:"message,omitempty"`
+}
+
+// DependencyHistory is the recorded history of a dependency, oldest entry first.
+type DependencyHistory struct {
+	Name    string         `json:"name"`
+	Type    string         `json:"type,omitempty"`
+	Entries []HistoryEntry `json:"entries"`
+}
+
+// HistorySummary summarizes the most recent checks of a dependency.
+type HistorySummary struct {
+	// Checks is the number of checks summarized, and Statuses counts them by
+	// status.
+	Checks   int            `json:"checks"`
+	Statuses map[Status]int `json:"statuses"`
+	// Changes is the number of times the status changed between them.
+	Changes int `json:"changes"`
+	// Recent lists their statuses, oldest first.
+	Recent []Status `json:"recent"`
+}
+
+// History keeps the most recent check results of each dependency in memory.
+// WithHistory records the handler's runs into it and adds a summary to each
+// dependency in the Response; to record without the summaries, register it
+// with WithObserver instead. Its Handler serves the recorded results. Results
+// are keyed by dependency name, so each handler needs a History of its own.
+type History struct {
+	size int
+
+	mu   sync.Mutex
+	deps map[string]*historyRing
+	now  func() time.Time
+}
+
+// historyRing is a fixed-size ring buffer of history entries.
+type historyRing struct {
+	typ     string
+	entries []HistoryEntry
+	next    int // index of the oldest entry once the ring is full
+}
+
+// NewHistory returns a History that keeps up to size entries per dependency.
+func NewHistory(size int) *History {
+	if size < 1 {
+		size = 1
+	}
+	return &History{
+		size: size,
+		deps: make(map[string]*historyRing),
+		now:  time.Now,
+	}
+}
+
+// ObserveRun implements Observer.
+func (h *History) ObserveRun(run CheckRun) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, r := range run.Results {
+		ring, ok := h.deps[r.Name]
+		if !ok {
+			ring = &historyRing{entries: make([]HistoryEntry, 0, h.size)}
+			h.deps[r.Name] = ring
+		}
+		ring.typ = r.Type
+		ring.add(HistoryEntry{
+			Time:     run.Time,
+			Status:   r.Status,
+			Duration: r.RequestDuration,
+			Message:  r.Message,
+		})
+	}
+}
+
+func (r *historyRing) add(e HistoryEntry) {
+	if len(r.entries) < cap(r.entries) {
+		r.entries = append(r.entries, e)
+		return
+	}
+	r.entries[r.next] = e
+	r.next = (r.next + 1) % len(r.entries)
+}
+
+// last returns up to n of the most recent entries, oldest first; n < 0 returns all.
+func (r *historyRing) last(n int) []HistoryEntry {
+	ordered := make([]HistoryEntry, 0, len(r.entries))
+	ordered = append(ordered, r.entries[r.next:]...)
+	ordered = append(ordered, r.entries[:r.next]...)
+	if n >= 0 && n < len(ordered) {
+		ordered = ordered[len(ordered)-n:]
+	}
+	return ordered
+}
+
+// Dependency returns the entries of the named dependency recorded from since
+// until until, oldest first. A zero since or until leaves that end open.
+func (h *History) Dependency(name string, since, until time.Time) []HistoryEntry {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	ring, ok := h.deps[name]
+	if !ok {
+		return nil
+	}
+	return filterEntries(ring.last(-1), since, until)
+}
+
+// All returns the history of every dependency, sorted by name, with the
+// entries recorded from since until until.
+func (h *History) All(since, until time.Time) []DependencyHistory {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	all := make([]DependencyHistory, 0, len(h.deps))
+	for name, ring := range h.deps {
+		all = append(all, DependencyHistory{
+			Name:    name,
+			Type:    ring.typ,
+			Entries: filterEntries(ring.last(-1), since, until),
+		})
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].Name < all[j].Name })
+	return all
+}
+
+func filterEntries(entries []HistoryEntry, since, until time.Time) []HistoryEntry {
+	filtered := entries[:0]
+	for _, e := range entries {
+		if (!since.IsZero() && e.Time.Before(since)) || (!until.IsZero() && e.Time.After(until)) {
+			continue
+		}
+		filtered = append(filtered, e)
+	}
+	return filtered
+}
+
+// Summary summarizes the last n checks of the named dependency. It returns nil
+// when none are recorded.
+func (h *History) Summary(name string, n int) *HistorySummary {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	ring, ok := h.deps[name]
+	if !ok || len(ring.entries) == 0 {
+		return nil
+	}
+
+	entries := ring.last(n)
+	s := &HistorySummary{
+		Checks:   len(entries),
+		Statuses: make(map[Status]int),
+		Recent:   make([]Status, len(entries)),
+	}
+	for i, e := range entries {
+		s.Statuses[e.Status]++
+		s.Recent[i] = e.Status
+		if i > 0 && e.Status != entries[i-1].Status {
+			s.Changes++
+		}
+	}
+	return s
+}
+
+// Handler returns a handler that serves the history as JSON: an object with a
+// "dependencies" array of DependencyHistory.
+//
+//	r.GET("/health/history", history.Handler())
+//
+// The optional query parameters select what is served:
+//
+//   - name: only the named dependency; repeat it for several.
+//   - since, until: only entries recorded in the range. Each is an RFC 3339
+//     time or a Go duration counting back from now, such as "1h".
+func (h *History) Handler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		since, err := h.parseTime(c.Query("since"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"message": fmt.Sprintf("invalid since: %v", err)})
+			return
+		}
+		until, err := h.parseTime(c.Query("until"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"message": fmt.Sprintf("invalid until: %v", err)})
+			return
+		}
+
+		deps := h.All(since, until)
+		if names := c.QueryArray("name"); len(names) > 0 {
+			selected := deps[:0]
+			for _, d := range deps {
+				if slices.Contains(names, d.Name) {
+					selected = append(selected, d)
+				}
+			}
+			deps = selected
+		}
+		c.JSON(http.StatusOK, gin.H{"dependencies": deps})
+	}
+}
+
+// parseTime parses an RFC 3339 time, or a duration before now.
+func (h *History) parseTime(s string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if d, err := time.ParseDuration(s); err == nil {
+		return h.now().Add(-d), nil
+	}
+	return time.Parse(time.RFC3339, s)
+}
+
+// WithHistory records the handler's check results in the History, and includes
+// a HistorySummary of the last summary checks of each dependency in the
+// Response. Pass a summary of 0 to leave the Response unchanged.
+func WithHistory(h *History, summary int) Option {
+	return func(cfg *config) {
+		if h == nil {
+			return
+		}
+		cfg.observers = append(cfg.observers, h)
+		if summary > 0 {
+			cfg.history = h
+			cfg.historySummary = summary
+		}
+	}
+}
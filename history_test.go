@@ -0,0 +1,147 @@
+package heartbeat_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/twistingmercury/heartbeat"
+)
+
+var historyStart = time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// observeStatuses records a run per status, a minute apart, for a "cache" dependency.
+func observeStatuses(h *heartbeat.History, statuses ...heartbeat.Status) {
+	for i, s := range statuses {
+		h.ObserveRun(heartbeat.CheckRun{
+			Service: "unit-test",
+			Time:    historyStart.Add(time.Duration(i) * time.Minute),
+			Results: []heartbeat.StatusResult{
+				{Name: "cache", Type: "redis", Status: s, RequestDuration: float64(i), Message: s.String()},
+			},
+		})
+	}
+}
+
+func TestHistoryKeepsMostRecentEntries(t *testing.T) {
+	h := heartbeat.NewHistory(3)
+	observeStatuses(h, heartbeat.StatusOK, heartbeat.StatusCritical, heartbeat.StatusOK, heartbeat.StatusCritical, heartbeat.StatusWarning)
+
+	entries := h.Dependency("cache", time.Time{}, time.Time{})
+	require.Len(t, entries, 3)
+	assert.Equal(t, heartbeat.StatusOK, entries[0].Status)
+	assert.Equal(t, historyStart.Add(2*time.Minute), entries[0].Time)
+	assert.Equal(t, heartbeat.StatusCritical, entries[1].Status)
+	assert.Equal(t, heartbeat.StatusWarning, entries[2].Status)
+	assert.Equal(t, float64(4), entries[2].Duration)
+	assert.Equal(t, "Warning", entries[2].Message)
+
+	entries = h.Dependency("cache", historyStart.Add(3*time.Minute), historyStart.Add(3*time.Minute))
+	require.Len(t, entries, 1)
+	assert.Equal(t, heartbeat.StatusCritical, entries[0].Status)
+
+	assert.Nil(t, h.Dependency("db", time.Time{}, time.Time{}))
+}
+
+func TestHistorySummary(t *testing.T) {
+	h := heartbeat.NewHistory(10)
+	assert.Nil(t, h.Summary("cache", 5))
+
+	observeStatuses(h, heartbeat.StatusCritical, heartbeat.StatusOK, heartbeat.StatusCritical, heartbeat.StatusOK, heartbeat.StatusOK, heartbeat.StatusOK)
+	s := h.Summary("cache", 5)
+	require.NotNil(t, s)
+	assert.Equal(t, 5, s.Checks)
+	assert.Equal(t, map[heartbeat.Status]int{heartbeat.StatusOK: 4, heartbeat.StatusCritical: 1}, s.Statuses)
+	assert.Equal(t, 2, s.Changes)
+	assert.Equal(t, []heartbeat.Status{heartbeat.StatusOK, heartbeat.StatusCritical, heartbeat.StatusOK, heartbeat.StatusOK, heartbeat.StatusOK}, s.Recent)
+}
+
+func historyResponse(t *testing.T, r *gin.Engine, target string) (int, []heartbeat.DependencyHistory) {
+	resp := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, target, nil)
+	r.ServeHTTP(resp, req)
+
+	var body struct {
+		Dependencies []heartbeat.DependencyHistory `json:"dependencies"`
+	}
+	if resp.Code == http.StatusOK {
+		require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &body))
+	}
+	return resp.Code, body.Dependencies
+}
+
+func TestHistoryHandler(t *testing.T) {
+	h := heartbeat.NewHistory(10)
+	h.SetClock(func() time.Time { return historyStart.Add(5 * time.Minute) })
+	observeStatuses(h, heartbeat.StatusOK, heartbeat.StatusCritical, heartbeat.StatusOK, heartbeat.StatusWarning)
+	h.ObserveRun(heartbeat.CheckRun{Time: historyStart, Results: []heartbeat.StatusResult{{Name: "db", Status: heartbeat.StatusOK}}})
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/history", h.Handler())
+
+	code, deps := historyResponse(t, r, "/history")
+	assert.Equal(t, http.StatusOK, code)
+	require.Len(t, deps, 2)
+	assert.Equal(t, "cache", deps[0].Name)
+	assert.Equal(t, "redis", deps[0].Type)
+	assert.Len(t, deps[0].Entries, 4)
+	assert.Equal(t, "db", deps[1].Name)
+
+	code, deps = historyResponse(t, r, "/history?name=cache&since=3m")
+	assert.Equal(t, http.StatusOK, code)
+	require.Len(t, deps, 1)
+	require.Len(t, deps[0].Entries, 2)
+	assert.Equal(t, historyStart.Add(2*time.Minute), deps[0].Entries[0].Time)
+
+	code, deps = historyResponse(t, r, "/history?name=cache&until="+historyStart.Add(time.Minute).Format(time.RFC3339))
+	assert.Equal(t, http.StatusOK, code)
+	require.Len(t, deps, 1)
+	assert.Len(t, deps[0].Entries, 2)
+
+	code, _ = historyResponse(t, r, "/history?since=yesterday")
+	assert.Equal(t, http.StatusBadRequest, code)
+}
+
+func TestWithHistoryAddsSummaryToResponse(t *testing.T) {
+	deps, status := switchableDeps()
+	h := heartbeat.NewHistory(100)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/health", heartbeat.NewHandler("unit-test", deps, heartbeat.WithHistory(h, 3)))
+
+	healthResponse(t, r)
+	status.Store(int32(heartbeat.StatusCritical))
+	healthResponse(t, r)
+	status.Store(int32(heartbeat.StatusOK))
+	healthResponse(t, r)
+	hb := healthResponse(t, r)
+
+	require.Len(t, hb.Dependencies, 2)
+	cache := hb.Dependencies[1]
+	require.NotNil(t, cache.History)
+	assert.Equal(t, 3, cache.History.Checks)
+	assert.Equal(t, 1, cache.History.Changes)
+	assert.Equal(t, []heartbeat.Status{heartbeat.StatusCritical, heartbeat.StatusOK, heartbeat.StatusOK}, cache.History.Recent)
+
+	assert.Len(t, h.Dependency("cache", time.Time{}, time.Time{}), 4)
+}
+
+func TestWithHistoryWithoutSummary(t *testing.T) {
+	deps, _ := switchableDeps()
+	h := heartbeat.NewHistory(100)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/health", heartbeat.NewHandler("unit-test", deps, heartbeat.WithHistory(h, 0)))
+
+	hb := healthResponse(t, r)
+	assert.Nil(t, hb.Dependencies[0].History)
+	assert.Len(t, h.Dependency("db", time.Time{}, time.Time{}), 1)
+}
@@ -0,0 +1,270 @@
+package heartbeat
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Headers of HMAC signed check requests, along with SignatureHeader.
+const (
+	TimestampHeader = "X-Heartbeat-Timestamp"
+	KeyIDHeader     = "X-Heartbeat-Key-Id"
+)
+
+// HTTPAuth authenticates the requests of an HTTP dependency check. An error
+// means no credentials could be obtained: the check reports StatusUnknown with
+// StatusResult.AuthFailed set, without calling the dependency.
+type HTTPAuth interface {
+	Authenticate(ctx context.Context, req *http.Request) error
+}
+
+// HTTPAuthFunc adapts a function to the HTTPAuth interface.
+type HTTPAuthFunc func(ctx context.Context, req *http.Request) error
+
+// Authenticate implements HTTPAuth.
+func (f HTTPAuthFunc) Authenticate(ctx context.Context, req *http.Request) error {
+	return f(ctx, req)
+}
+
+// BasicAuth authenticates with HTTP basic authentication.
+func BasicAuth(username string, password Secret) HTTPAuth {
+	return HTTPAuthFunc(func(ctx context.Context, req *http.Request) error {
+		pw, err := password.Resolve(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to resolve password: %w", err)
+		}
+		req.SetBasicAuth(username, pw)
+		return nil
+	})
+}
+
+// BearerAuth authenticates with a static bearer token.
+func BearerAuth(token Secret) HTTPAuth {
+	return HTTPAuthFunc(func(ctx context.Context, req *http.Request) error {
+		t, err := token.Resolve(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to resolve token: %w", err)
+		}
+		req.Header.Set("Authorization", "Bearer "+t)
+		return nil
+	})
+}
+
+// HMACAuth signs check requests with HMAC-SHA256. The TimestampHeader holds
+// the Unix time in seconds and the SignatureHeader the Sign value of
+//
+//	METHOD + "\n" + REQUEST_URI + "\n" + TIMESTAMP
+//
+// such as "GET\n/health?deep=1\n1700000000". The KeyIDHeader names the key
+// when keyID is not empty, for dependencies that rotate keys.
+func HMACAuth(keyID string, key Secret) HTTPAuth {
+	return HTTPAuthFunc(func(ctx context.Context, req *http.Request) error {
+		k, err := key.Resolve(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to resolve HMAC key: %w", err)
+		}
+		ts := strconv.FormatInt(time.Now().Unix(), 10)
+		req.Header.Set(TimestampHeader, ts)
+		req.Header.Set(SignatureHeader, Sign(k, []byte(req.Method+"\n"+req.URL.RequestURI()+"\n"+ts)))
+		if keyID != "" {
+			req.Header.Set(KeyIDHeader, keyID)
+		}
+		return nil
+	})
+}
+
+// defaultExpiryDelta is how long before their expiry OAuth2 tokens are refreshed.
+const defaultExpiryDelta = 30 * time.Second
+
+// OAuth2Config configures an OAuth2 client credentials grant.
+type OAuth2Config struct {
+	TokenURL     string
+	ClientID     string
+	ClientSecret Secret
+	Scopes       []string
+	// EndpointParams are further parameters of the token request, such as an
+	// audience.
+	EndpointParams url.Values
+	// CredentialsInBody sends the client credentials as form parameters
+	// instead of with HTTP basic authentication.
+	CredentialsInBody bool
+	// ExpiryDelta refreshes tokens this long before they expire. Defaults to
+	// 30 seconds.
+	ExpiryDelta time.Duration
+	// Client makes the token requests. Defaults to a client without a timeout;
+	// requests are bounded by the check's timeout.
+	Client *http.Client
+}
+
+// OAuth2ClientCredentials authenticates with bearer tokens obtained with the
+// OAuth2 client credentials grant. Tokens are cached until shortly before
+// they expire, and dropped when the dependency rejects them with 401
+// Unauthorized, so that the next check fetches a new one.
+//
+// The token is cached in the OAuth2ClientCredentials value, so make it once
+// with NewOAuth2ClientCredentials instead of per check; a new value fetches a
+// new token. Dependencies using the same credentials can share it.
+type OAuth2ClientCredentials struct {
+	cfg OAuth2Config
+
+	mu     sync.Mutex
+	token  string
+	expiry time.Time // zero when the token does not expire
+	now    func() time.Time
+}
+
+// NewOAuth2ClientCredentials returns an OAuth2ClientCredentials without a
+// cached token.
+func NewOAuth2ClientCredentials(cfg OAuth2Config) *OAuth2ClientCredentials {
+	if cfg.ExpiryDelta <= 0 {
+		cfg.ExpiryDelta = defaultExpiryDelta
+	}
+	if cfg.Client == nil {
+		cfg.Client = &http.Client{}
+	}
+	return &OAuth2ClientCredentials{cfg: cfg, now: time.Now}
+}
+
+// Authenticate implements HTTPAuth.
+func (o *OAuth2ClientCredentials) Authenticate(ctx context.Context, req *http.Request) error {
+	token, err := o.Token(ctx)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	return nil
+}
+
+// Token returns the cached access token, fetching a new one when there is
+// none or it is about to expire.
+func (o *OAuth2ClientCredentials) Token(ctx context.Context) (string, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.token != "" && (o.expiry.IsZero() || o.now().Add(o.cfg.ExpiryDelta).Before(o.expiry)) {
+		return o.token, nil
+	}
+
+	token, expiresIn, err := o.fetch(ctx)
+	if err != nil {
+		o.token = ""
+		return "", err
+	}
+	o.token = token
+	o.expiry = time.Time{}
+	if expiresIn > 0 {
+		o.expiry = o.now().Add(time.Duration(expiresIn) * time.Second)
+	}
+	return o.token, nil
+}
+
+// Invalidate drops the cached token.
+func (o *OAuth2ClientCredentials) Invalidate() {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.token = ""
+}
+
+// tokenResponse is a token endpoint response, successful or not.
+type tokenResponse struct {
+	AccessToken      string `json:"access_token"`
+	TokenType        string `json:"token_type"`
+	ExpiresIn        int64  `json:"expires_in"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+func (o *OAuth2ClientCredentials) fetch(ctx context.Context) (string, int64, error) {
+	var secret string
+	if o.cfg.ClientSecret != nil {
+		var err error
+		if secret, err = o.cfg.ClientSecret.Resolve(ctx); err != nil {
+			return "", 0, fmt.Errorf("failed to resolve client secret: %w", err)
+		}
+	}
+
+	form := url.Values{"grant_type": {"client_credentials"}}
+	for k, v := range o.cfg.EndpointParams {
+		form[k] = v
+	}
+	if len(o.cfg.Scopes) > 0 {
+		form.Set("scope", strings.Join(o.cfg.Scopes, " "))
+	}
+	if o.cfg.CredentialsInBody {
+		form.Set("client_id", o.cfg.ClientID)
+		form.Set("client_secret", secret)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, o.cfg.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to create token request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if !o.cfg.CredentialsInBody {
+		req.SetBasicAuth(url.QueryEscape(o.cfg.ClientID), url.QueryEscape(secret))
+	}
+
+	resp, err := o.cfg.Client.Do(req)
+	if err != nil {
+		return "", 0, fmt.Errorf("token request failed: %w", err)
+	}
+	defer func() {
+		_ = resp.Body.Close() // Error intentionally ignored - the body has been read
+	}()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to read token response: %w", err)
+	}
+
+	var tr tokenResponse
+	jsonErr := json.Unmarshal(body, &tr)
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		msg := fmt.Sprintf("token endpoint returned HTTP %d", resp.StatusCode)
+		if jsonErr == nil && tr.Error != "" {
+			msg += ": " + tr.Error
+			if tr.ErrorDescription != "" {
+				msg += " (" + tr.ErrorDescription + ")"
+			}
+		}
+		return "", 0, errors.New(msg)
+	}
+	if jsonErr != nil {
+		return "", 0, fmt.Errorf("invalid token response: %w", jsonErr)
+	}
+	if tr.AccessToken == "" {
+		return "", 0, errors.New("token response has no access_token")
+	}
+	if tr.TokenType != "" && !strings.EqualFold(tr.TokenType, "bearer") {
+		return "", 0, fmt.Errorf("unsupported token type %q", tr.TokenType)
+	}
+	return tr.AccessToken, tr.ExpiresIn, nil
+}
+
+// authenticate applies the auth to the check request, reporting a failure
+// as StatusUnknown.
+func authenticate(ctx context.Context, auth HTTPAuth, req *http.Request, hsr *StatusResult) bool {
+	if auth == nil {
+		return true
+	}
+	if err := auth.Authenticate(ctx, req); err != nil {
+		hsr.Status = StatusUnknown
+		hsr.AuthFailed = true
+		hsr.Message = fmt.Sprintf("authentication failed: %v", err)
+		return false
+	}
+	return true
+}
+
+// invalidator is implemented by HTTPAuth that cache credentials.
+type invalidator interface {
+	Invalidate()
+}
@@ -0,0 +1,277 @@
+package heartbeat_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/twistingmercury/heartbeat"
+)
+
+func staticSecret(v string) heartbeat.Secret {
+	return heartbeat.SecretFunc(func(context.Context) (string, error) { return v, nil })
+}
+
+func failingSecret(err error) heartbeat.Secret {
+	return heartbeat.SecretFunc(func(context.Context) (string, error) { return "", err })
+}
+
+func checkWithAuth(t *testing.T, handler http.HandlerFunc, auth heartbeat.HTTPAuth) heartbeat.StatusResult {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	defer server.Close()
+	return heartbeat.CheckDependency(context.Background(), heartbeat.DependencyDescriptor{
+		Name:       "api",
+		Connection: server.URL + "/health?deep=1",
+		Timeout:    time.Second,
+		Auth:       auth,
+	})
+}
+
+func TestBasicAuth(t *testing.T) {
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		user, pass, ok := r.BasicAuth()
+		if !ok || user != "monitor" || pass != "s3cret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+
+	hsr := checkWithAuth(t, handler, heartbeat.BasicAuth("monitor", staticSecret("s3cret")))
+	assert.Equal(t, heartbeat.StatusOK, hsr.Status, hsr.Message)
+
+	hsr = checkWithAuth(t, handler, nil)
+	assert.Equal(t, heartbeat.StatusCritical, hsr.Status)
+	assert.False(t, hsr.AuthFailed)
+}
+
+func TestBearerAuth(t *testing.T) {
+	hsr := checkWithAuth(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer t0ken", r.Header.Get("Authorization"))
+		w.WriteHeader(http.StatusOK)
+	}, heartbeat.BearerAuth(staticSecret("t0ken")))
+	assert.Equal(t, heartbeat.StatusOK, hsr.Status, hsr.Message)
+}
+
+func TestHMACAuth(t *testing.T) {
+	hsr := checkWithAuth(t, func(w http.ResponseWriter, r *http.Request) {
+		ts := r.Header.Get(heartbeat.TimestampHeader)
+		unix, err := strconv.ParseInt(ts, 10, 64)
+		require.NoError(t, err)
+		assert.InDelta(t, time.Now().Unix(), unix, 5)
+		assert.Equal(t, "key-1", r.Header.Get(heartbeat.KeyIDHeader))
+		assert.Equal(t, heartbeat.Sign("hmac-key", []byte("GET\n/health?deep=1\n"+ts)), r.Header.Get(heartbeat.SignatureHeader))
+		w.WriteHeader(http.StatusOK)
+	}, heartbeat.HMACAuth("key-1", staticSecret("hmac-key")))
+	assert.Equal(t, heartbeat.StatusOK, hsr.Status, hsr.Message)
+}
+
+func TestAuthFailureReportedSeparately(t *testing.T) {
+	var called atomic.Bool
+	hsr := checkWithAuth(t, func(w http.ResponseWriter, r *http.Request) {
+		called.Store(true)
+		w.WriteHeader(http.StatusOK)
+	}, heartbeat.BearerAuth(failingSecret(errors.New("vault sealed"))))
+
+	assert.False(t, called.Load(), "the dependency is not called without credentials")
+	assert.Equal(t, heartbeat.StatusUnknown, hsr.Status)
+	assert.True(t, hsr.AuthFailed)
+	assert.Equal(t, "authentication failed: failed to resolve token: vault sealed", hsr.Message)
+}
+
+func TestAuthFailuresRetriedOnlyWhenSelected(t *testing.T) {
+	var attempts atomic.Int32
+	auth := heartbeat.HTTPAuthFunc(func(ctx context.Context, req *http.Request) error {
+		if attempts.Add(1) < 2 {
+			return errors.New("token endpoint unavailable")
+		}
+		return nil
+	})
+	handler := func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) }
+	server := httptest.NewServer(http.HandlerFunc(handler))
+	defer server.Close()
+
+	d := heartbeat.DependencyDescriptor{
+		Name:       "api",
+		Connection: server.URL,
+		Timeout:    time.Second,
+		Auth:       auth,
+		Retry:      &heartbeat.RetryPolicy{Attempts: 3},
+	}
+	hsr := heartbeat.CheckDependency(context.Background(), d)
+	assert.Equal(t, heartbeat.StatusUnknown, hsr.Status)
+	assert.Equal(t, 1, hsr.Attempts)
+
+	attempts.Store(0)
+	d.Retry.On = heartbeat.RetryAuthFailures
+	hsr = heartbeat.CheckDependency(context.Background(), d)
+	assert.Equal(t, heartbeat.StatusOK, hsr.Status, hsr.Message)
+	assert.Equal(t, 2, hsr.Attempts)
+}
+
+func TestAuthSharesCheckTimeout(t *testing.T) {
+	slowToken := heartbeat.SecretFunc(func(context.Context) (string, error) {
+		time.Sleep(150 * time.Millisecond)
+		return "token", nil
+	})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(150 * time.Millisecond):
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+
+	st := time.Now()
+	hsr := heartbeat.CheckDependency(context.Background(), heartbeat.DependencyDescriptor{
+		Name:       "api",
+		Connection: server.URL,
+		Timeout:    200 * time.Millisecond,
+		Auth:       heartbeat.BearerAuth(slowToken),
+	})
+	assert.Less(t, time.Since(st), 280*time.Millisecond, "the request gets what the credentials left of the timeout")
+	assert.Equal(t, heartbeat.StatusCritical, hsr.Status)
+}
+
+// tokenServer issues numbered tokens, expiring after expiresIn seconds.
+func tokenServer(t *testing.T, expiresIn int) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var issued atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		id, secret, ok := r.BasicAuth()
+		if !ok || id != "client" || secret != "s3cret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			_ = json.NewEncoder(w).Encode(map[string]string{"error": "invalid_client", "error_description": "bad credentials"})
+			return
+		}
+		assert.Equal(t, "client_credentials", r.PostForm.Get("grant_type"))
+		assert.Equal(t, "health:read", r.PostForm.Get("scope"))
+		assert.Equal(t, "orders", r.PostForm.Get("audience"))
+
+		n := issued.Add(1)
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"access_token": "token-" + strconv.Itoa(int(n)),
+			"token_type":   "Bearer",
+			"expires_in":   expiresIn,
+		})
+	}))
+	t.Cleanup(server.Close)
+	return server, &issued
+}
+
+func TestOAuth2ClientCredentialsCachesAndRefreshes(t *testing.T) {
+	server, issued := tokenServer(t, 300)
+	oauth := heartbeat.NewOAuth2ClientCredentials(heartbeat.OAuth2Config{
+		TokenURL:       server.URL,
+		ClientID:       "client",
+		ClientSecret:   staticSecret("s3cret"),
+		Scopes:         []string{"health:read"},
+		EndpointParams: map[string][]string{"audience": {"orders"}},
+	})
+	now := time.Now()
+	oauth.SetClock(func() time.Time { return now })
+
+	token, err := oauth.Token(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "token-1", token)
+
+	token, err = oauth.Token(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "token-1", token, "cached")
+
+	// Refreshed within the expiry delta of the expiry.
+	now = now.Add(300*time.Second - 20*time.Second)
+	token, err = oauth.Token(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "token-2", token)
+
+	oauth.Invalidate()
+	token, err = oauth.Token(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "token-3", token)
+	assert.Equal(t, int32(3), issued.Load())
+}
+
+func TestOAuth2ClientCredentialsCheck(t *testing.T) {
+	tokens, issued := tokenServer(t, 3600)
+	oauth := heartbeat.NewOAuth2ClientCredentials(heartbeat.OAuth2Config{
+		TokenURL:       tokens.URL,
+		ClientID:       "client",
+		ClientSecret:   staticSecret("s3cret"),
+		Scopes:         []string{"health:read"},
+		EndpointParams: map[string][]string{"audience": {"orders"}},
+	})
+
+	var accepted atomic.Value
+	accepted.Store("Bearer token-1")
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != accepted.Load().(string) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+
+	hsr := checkWithAuth(t, handler, oauth)
+	assert.Equal(t, heartbeat.StatusOK, hsr.Status, hsr.Message)
+	hsr = checkWithAuth(t, handler, oauth)
+	assert.Equal(t, heartbeat.StatusOK, hsr.Status, hsr.Message)
+	assert.Equal(t, int32(1), issued.Load())
+
+	// A rejected token is a failure of the dependency, and is replaced for the next check.
+	accepted.Store("Bearer token-2")
+	hsr = checkWithAuth(t, handler, oauth)
+	assert.Equal(t, heartbeat.StatusCritical, hsr.Status)
+	assert.False(t, hsr.AuthFailed)
+	hsr = checkWithAuth(t, handler, oauth)
+	assert.Equal(t, heartbeat.StatusOK, hsr.Status, hsr.Message)
+	assert.Equal(t, int32(2), issued.Load())
+}
+
+func TestOAuth2ClientCredentialsTokenFailure(t *testing.T) {
+	tokens, _ := tokenServer(t, 3600)
+	oauth := heartbeat.NewOAuth2ClientCredentials(heartbeat.OAuth2Config{
+		TokenURL:     tokens.URL,
+		ClientID:     "client",
+		ClientSecret: staticSecret("wrong"),
+	})
+
+	hsr := checkWithAuth(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}, oauth)
+	assert.Equal(t, heartbeat.StatusUnknown, hsr.Status)
+	assert.True(t, hsr.AuthFailed)
+	assert.Equal(t, "authentication failed: token endpoint returned HTTP 401: invalid_client (bad credentials)", hsr.Message)
+}
+
+func TestOAuth2ClientCredentialsInBody(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		_, _, basic := r.BasicAuth()
+		assert.False(t, basic)
+		assert.Equal(t, "client", r.PostForm.Get("client_id"))
+		assert.Equal(t, "s3cret", r.PostForm.Get("client_secret"))
+		_ = json.NewEncoder(w).Encode(map[string]any{"access_token": "abc"})
+	}))
+	defer server.Close()
+
+	oauth := heartbeat.NewOAuth2ClientCredentials(heartbeat.OAuth2Config{
+		TokenURL:          server.URL,
+		ClientID:          "client",
+		ClientSecret:      staticSecret("s3cret"),
+		CredentialsInBody: true,
+	})
+	token, err := oauth.Token(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "abc", token)
+}
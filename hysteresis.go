@@ -0,0 +1,94 @@
+package heartbeat
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// Hysteresis damps status flapping of a dependency. The reported status only
+// degrades after DegradeAfter consecutive results worse than it, and only
+// recovers after RecoverAfter consecutive results better than it. The first
+// result is reported as is.
+//
+// The reported status and the run of results against it carry over from one
+// health request to the next, so give every dependency its own Hysteresis
+// from NewHysteresis, created along with the handler.
+type Hysteresis struct {
+	degradeAfter int
+	recoverAfter int
+
+	mu       sync.Mutex
+	reported Status
+	worse    int
+	better   int
+}
+
+// NewHysteresis returns a Hysteresis that requires degradeAfter consecutive bad
+// results before degrading and recoverAfter consecutive good results before
+// recovering. Values below 1 are treated as 1.
+func NewHysteresis(degradeAfter, recoverAfter int) *Hysteresis {
+	return &Hysteresis{
+		degradeAfter: max(degradeAfter, 1),
+		recoverAfter: max(recoverAfter, 1),
+	}
+}
+
+// Status returns the currently reported (damped) status.
+func (h *Hysteresis) Status() Status {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.reported
+}
+
+// apply feeds a raw result into the hysteresis and returns the damped result.
+// The raw status is kept in RawStatus.
+func (h *Hysteresis) apply(hsr StatusResult) StatusResult {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	hsr.RawStatus = hsr.Status
+
+	var count, needed int
+	switch {
+	case h.reported == StatusNotSet || hsr.Status == h.reported:
+		h.reported = hsr.Status
+		h.worse, h.better = 0, 0
+		return hsr
+	case hsr.Status.Severity() > h.reported.Severity():
+		h.worse++
+		h.better = 0
+		count, needed = h.worse, h.degradeAfter
+	default:
+		h.better++
+		h.worse = 0
+		count, needed = h.better, h.recoverAfter
+	}
+
+	if count >= needed {
+		h.reported = hsr.Status
+		h.worse, h.better = 0, 0
+		return hsr
+	}
+
+	hsr.Status = h.reported
+	held := fmt.Sprintf("held at %s: %d of %d consecutive %s results", h.reported, count, needed, hsr.RawStatus)
+	if hsr.Message == "" {
+		hsr.Message = held
+	} else {
+		hsr.Message = fmt.Sprintf("%s (%s)", hsr.Message, held)
+	}
+	return hsr
+}
+
+// checkWithHysteresis runs the check and damps its status through the
+// dependency's Hysteresis.
+func checkWithHysteresis(ctx context.Context, d DependencyDescriptor, check func() StatusResult) StatusResult {
+	hsr := check()
+	if callerGone(ctx) {
+		// Cut short by the caller; the result says nothing about the dependency.
+		hsr.RawStatus = hsr.Status
+		return hsr
+	}
+	return d.Hysteresis.apply(hsr)
+}
@@ -0,0 +1,72 @@
+package heartbeat_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/twistingmercury/heartbeat"
+)
+
+// sequenceDep returns a dependency that reports the given statuses in order.
+func sequenceDep(h *heartbeat.Hysteresis, statuses ...heartbeat.Status) heartbeat.DependencyDescriptor {
+	i := 0
+	return heartbeat.DependencyDescriptor{
+		Name: "flapping",
+		HandlerFunc: func() heartbeat.StatusResult {
+			s := statuses[i]
+			i++
+			return heartbeat.StatusResult{Status: s, Message: s.String()}
+		},
+		Hysteresis: h,
+	}
+}
+
+func TestHysteresisDampsTransitions(t *testing.T) {
+	ok, warn, crit := heartbeat.StatusOK, heartbeat.StatusWarning, heartbeat.StatusCritical
+	raw := []heartbeat.Status{ok, warn, ok, warn, warn, warn, ok, warn, ok, ok, crit}
+	exp := []heartbeat.Status{ok, ok, ok, ok, ok, warn, warn, warn, warn, ok, ok}
+
+	h := heartbeat.NewHysteresis(3, 2)
+	d := sequenceDep(h, raw...)
+
+	for i := range raw {
+		result := heartbeat.CheckDependency(context.Background(), d)
+		assert.Equal(t, exp[i], result.Status, "check %d", i)
+		assert.Equal(t, raw[i], result.RawStatus, "check %d", i)
+	}
+	assert.Equal(t, heartbeat.StatusOK, h.Status())
+}
+
+func TestHysteresisHeldMessage(t *testing.T) {
+	d := sequenceDep(heartbeat.NewHysteresis(2, 1), heartbeat.StatusOK, heartbeat.StatusCritical)
+
+	heartbeat.CheckDependency(context.Background(), d)
+	result := heartbeat.CheckDependency(context.Background(), d)
+	assert.Equal(t, heartbeat.StatusOK, result.Status)
+	assert.Equal(t, "Critical (held at OK: 1 of 2 consecutive Critical results)", result.Message)
+}
+
+func TestHysteresisRawStatusJSON(t *testing.T) {
+	d := sequenceDep(heartbeat.NewHysteresis(2, 1), heartbeat.StatusOK, heartbeat.StatusWarning)
+
+	heartbeat.CheckDependency(context.Background(), d)
+	result := heartbeat.CheckDependency(context.Background(), d)
+
+	data, err := json.Marshal(result)
+	assert.NoError(t, err)
+	assert.Contains(t, string(data), `"status":"OK"`)
+	assert.Contains(t, string(data), `"raw_status":"Warning"`)
+}
+
+func TestHysteresisIgnoresCancelledRequests(t *testing.T) {
+	h := heartbeat.NewHysteresis(1, 1)
+	d := sequenceDep(h, heartbeat.StatusOK, heartbeat.StatusCritical)
+	heartbeat.CheckDependency(context.Background(), d)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	heartbeat.CheckDependency(ctx, d)
+	assert.Equal(t, heartbeat.StatusOK, h.Status())
+}
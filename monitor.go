@@ -0,0 +1,62 @@
+package heartbeat
+
+import (
+	"context"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// defaultMonitorInterval applies to a Monitor run without an interval.
+const defaultMonitorInterval = 30 * time.Second
+
+// Monitor runs the dependency checks of a service on an interval and hands
+// every run to the observers of its options, so that a Notifier, a StatusFeed
+// or WithLogger learn of status changes without anything calling the health
+// endpoint. Its Handler serves the endpoint from the same checks:
+//
+//	m := heartbeat.NewMonitor("orders", deps, heartbeat.WithObserver(notifier))
+//	go m.Run(ctx, 30*time.Second)
+//	r.GET("/health", m.Handler())
+//
+// Runs started by the monitor and by requests share the execution limits,
+// coalescing and observers of the options, so each run is observed once.
+type Monitor struct {
+	h *handler
+}
+
+// NewMonitor returns a Monitor of the dependencies, configured by the given
+// handler options.
+func NewMonitor(svcName string, deps []DependencyDescriptor, opts ...Option) *Monitor {
+	return &Monitor{h: newHandler(svcName, deps, opts)}
+}
+
+// Handler returns the health endpoint of the monitored service.
+func (m *Monitor) Handler() gin.HandlerFunc {
+	return m.h.serve
+}
+
+// Run checks the dependencies right away and then every interval, 30 seconds
+// when it is not positive, until ctx is done. Each run is bounded by the
+// WithDeadline deadline; a run cut short by ctx is not observed.
+func (m *Monitor) Run(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = defaultMonitorInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		m.check(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (m *Monitor) check(ctx context.Context) {
+	ctx, cancel := withDeadline(ctx, deadlineExceeded{budget: m.h.cfg.deadline})
+	defer cancel()
+	m.h.check(ctx)
+}
@@ -0,0 +1,74 @@
+package heartbeat_test
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/twistingmercury/heartbeat"
+)
+
+func TestMonitorRunsChecksUntilCancelled(t *testing.T) {
+	deps, _ := switchableDeps()
+	var runs atomic.Int32
+	m := heartbeat.NewMonitor("unit-test", deps, heartbeat.WithObserver(heartbeat.ObserverFunc(func(run heartbeat.CheckRun) {
+		assert.Equal(t, "unit-test", run.Service)
+		assert.Len(t, run.Results, 2)
+		runs.Add(1)
+	})))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		m.Run(ctx, 10*time.Millisecond)
+		close(done)
+	}()
+
+	require.Eventually(t, func() bool { return runs.Load() >= 3 }, time.Second, time.Millisecond)
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		require.FailNow(t, "Run did not return after cancellation")
+	}
+}
+
+func TestMonitorNotifiesWithoutRequests(t *testing.T) {
+	srv, requests := webhookServer(t)
+	deps, status := switchableDeps()
+	n := heartbeat.NewNotifier(heartbeat.Webhook{URL: srv.URL, ExcludeAggregate: true})
+	defer n.Close()
+	m := heartbeat.NewMonitor("unit-test", deps, heartbeat.WithObserver(n))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go m.Run(ctx, 10*time.Millisecond)
+
+	status.Store(int32(heartbeat.StatusCritical))
+	got := waitForRequests(t, requests, 1)
+	assert.Contains(t, string(got[0].body), `"dependency":"cache"`)
+}
+
+func TestMonitorHandlerSharesObservers(t *testing.T) {
+	deps, _ := switchableDeps()
+	uptime := heartbeat.NewUptime(heartbeat.UptimeOptions{})
+	m := heartbeat.NewMonitor("unit-test", deps, heartbeat.WithUptime(uptime, true))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go m.Run(ctx, time.Hour)
+	require.Eventually(t, func() bool {
+		return uptime.Report("unit-test").Service.Availability[0].Checks == 1
+	}, time.Second, time.Millisecond)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/health", m.Handler())
+	hcr := healthResponse(t, r)
+	require.NotNil(t, hcr.Uptime)
+	assert.Equal(t, 2, hcr.Uptime.Service.Availability[0].Checks)
+}
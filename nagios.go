@@ -0,0 +1,242 @@
+package heartbeat
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Metric is a single performance data value reported by a check, modelled on
+// the Nagios plugin perfdata format: 'label'=value[UOM];[warn];[crit];[min];[max].
+type Metric struct {
+	Label string   `json:"label"`
+	Value float64  `json:"value"`
+	Unit  string   `json:"unit,omitempty"`
+	Warn  string   `json:"warn,omitempty"`
+	Crit  string   `json:"crit,omitempty"`
+	Min   *float64 `json:"min,omitempty"`
+	Max   *float64 `json:"max,omitempty"`
+}
+
+// parsePluginOutput splits Nagios plugin output into the first line of text and
+// the performance data found after the '|' separators on any line.
+func parsePluginOutput(output string) (message string, metrics []Metric) {
+	lines := strings.Split(strings.TrimRight(output, "\r\n"), "\n")
+	for i, line := range lines {
+		text, perf, _ := strings.Cut(line, "|")
+		if i == 0 {
+			message = strings.TrimSpace(text)
+		}
+		if perf != "" {
+			metrics = append(metrics, parsePerfData(perf)...)
+		}
+	}
+	return message, metrics
+}
+
+// parsePerfData parses a space separated list of perfdata items. Malformed items
+// and items with an undetermined ("U") value are skipped.
+func parsePerfData(perf string) []Metric {
+	var metrics []Metric
+	for _, item := range splitPerfData(perf) {
+		label, rest, ok := strings.Cut(item, "=")
+		if !ok {
+			continue
+		}
+		label = strings.ReplaceAll(strings.Trim(label, "'"), "''", "'")
+		if label == "" {
+			continue
+		}
+
+		fields := strings.Split(rest, ";")
+		num, unit := splitUnit(fields[0])
+		value, err := strconv.ParseFloat(num, 64)
+		if err != nil {
+			continue
+		}
+
+		m := Metric{Label: label, Value: value, Unit: unit}
+		if len(fields) > 1 {
+			m.Warn = fields[1]
+		}
+		if len(fields) > 2 {
+			m.Crit = fields[2]
+		}
+		if len(fields) > 3 {
+			m.Min = parseOptionalFloat(fields[3])
+		}
+		if len(fields) > 4 {
+			m.Max = parseOptionalFloat(fields[4])
+		}
+		metrics = append(metrics, m)
+	}
+	return metrics
+}
+
+// splitPerfData splits perfdata on whitespace, keeping single-quoted labels
+// that contain spaces intact.
+func splitPerfData(perf string) []string {
+	var items []string
+	var sb strings.Builder
+	quoted := false
+	for _, r := range perf {
+		switch {
+		case r == '\'':
+			quoted = !quoted
+			sb.WriteRune(r)
+		case (r == ' ' || r == '\t') && !quoted:
+			if sb.Len() > 0 {
+				items = append(items, sb.String())
+				sb.Reset()
+			}
+		default:
+			sb.WriteRune(r)
+		}
+	}
+	if sb.Len() > 0 {
+		items = append(items, sb.String())
+	}
+	return items
+}
+
+func splitUnit(s string) (num, unit string) {
+	i := strings.IndexFunc(s, func(r rune) bool {
+		return (r < '0' || r > '9') && r != '.' && r != '-' && r != '+' && r != 'e' && r != 'E'
+	})
+	if i < 0 {
+		return s, ""
+	}
+	return s[:i], s[i:]
+}
+
+func parseOptionalFloat(s string) *float64 {
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return nil
+	}
+	return &v
+}
+
+// RenderNagios renders the Response as Nagios plugin output. The HTTP status
+// code carries the exit code semantics, by default 200 for OK and WARNING and
+// 503 for CRITICAL and UNKNOWN, as mapped by WithStatusCodes.
+func RenderNagios(c *gin.Context, httpStatus int, resp Response) {
+	c.Data(httpStatus, "text/plain; charset=utf-8", []byte(resp.NagiosString()))
+}
+
+// NagiosString formats the Response as Nagios plugin output: a status line with
+// the duration of every dependency check as perfdata, followed by one line per
+// dependency. Each duration is warned at the slow response threshold and
+// critical at the dependency's timeout. "|" in names and messages is replaced
+// by "/" so that it isn't taken for the start of perfdata.
+func (h *Response) NagiosString() string {
+	var sb strings.Builder
+
+	summary := h.Message
+	if summary == "" {
+		summary = nagiosSummary(h.Dependencies)
+	}
+	if h.Name != "" {
+		summary = h.Name + ": " + summary
+	}
+	fmt.Fprintf(&sb, "%s - %s", nagiosState(h.Status), nagiosText(summary))
+
+	var perf []string
+	for _, dep := range h.Dependencies {
+		label := perfLabel(dep.Name)
+		timeout := dep.timeout
+		if timeout == 0 {
+			timeout = defaultTimeout
+		}
+		perf = append(perf, fmt.Sprintf("%s_ms=%s;%d;%d", label,
+			formatPerfValue(dep.RequestDuration), slowResponseThreshold.Milliseconds(), timeout.Milliseconds()))
+		for _, m := range dep.Metrics {
+			perf = append(perf, formatMetric(label+"_"+perfLabel(m.Label), m))
+		}
+	}
+	if len(perf) > 0 {
+		sb.WriteString(" | ")
+		sb.WriteString(strings.Join(perf, " "))
+	}
+
+	for _, dep := range h.Dependencies {
+		fmt.Fprintf(&sb, "\n%s - %s", nagiosState(dep.Status), nagiosText(dep.Name))
+		if msg := firstLine(dep.Message); msg != "" {
+			sb.WriteString(": " + nagiosText(msg))
+		}
+	}
+	sb.WriteString("\n")
+	return sb.String()
+}
+
+// nagiosState returns the Nagios service state name for a Status.
+func nagiosState(s Status) string {
+	switch s {
+	case StatusWarning:
+		return "WARNING"
+	case StatusCritical:
+		return "CRITICAL"
+	case StatusUnknown:
+		return "UNKNOWN"
+	default:
+		return "OK"
+	}
+}
+
+func nagiosSummary(deps []StatusResult) string {
+	if len(deps) == 0 {
+		return "no dependencies"
+	}
+	ok := 0
+	for _, dep := range deps {
+		if dep.Status == StatusOK {
+			ok++
+		}
+	}
+	return fmt.Sprintf("%d of %d dependencies OK", ok, len(deps))
+}
+
+// perfLabel reduces a name to a perfdata label that needs no quoting.
+func perfLabel(name string) string {
+	label := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+			return r
+		case r >= 'A' && r <= 'Z':
+			return r + ('a' - 'A')
+		default:
+			return '_'
+		}
+	}, name)
+	if label == "" {
+		return "unnamed"
+	}
+	return label
+}
+
+func formatMetric(label string, m Metric) string {
+	fields := []string{formatPerfValue(m.Value) + m.Unit, m.Warn, m.Crit, "", ""}
+	if m.Min != nil {
+		fields[3] = formatPerfValue(*m.Min)
+	}
+	if m.Max != nil {
+		fields[4] = formatPerfValue(*m.Max)
+	}
+	return label + "=" + strings.TrimRight(strings.Join(fields, ";"), ";")
+}
+
+func formatPerfValue(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+// nagiosText replaces the "|" that separates text from perfdata.
+func nagiosText(s string) string {
+	return strings.ReplaceAll(s, "|", "/")
+}
+
+func firstLine(s string) string {
+	line, _, _ := strings.Cut(s, "\n")
+	return strings.TrimSpace(line)
+}
@@ -0,0 +1,111 @@
+package heartbeat_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/twistingmercury/heartbeat"
+)
+
+func TestParsePluginOutput(t *testing.T) {
+	msg, metrics := heartbeat.ParsePluginOutput("PING OK - rta 0.5ms | 'round trip'=0.5ms;100;500;0; loss=0%;20;60 bad U=U\n")
+	assert.Equal(t, "PING OK - rta 0.5ms", msg)
+	if assert.Len(t, metrics, 2) {
+		assert.Equal(t, "round trip", metrics[0].Label)
+		assert.Equal(t, 0.5, metrics[0].Value)
+		assert.Equal(t, "ms", metrics[0].Unit)
+		assert.Equal(t, "100", metrics[0].Warn)
+		assert.Equal(t, "500", metrics[0].Crit)
+		if assert.NotNil(t, metrics[0].Min) {
+			assert.Equal(t, 0.0, *metrics[0].Min)
+		}
+		assert.Nil(t, metrics[0].Max)
+		assert.Equal(t, "loss", metrics[1].Label)
+		assert.Equal(t, "%", metrics[1].Unit)
+	}
+}
+
+func TestParsePluginOutputWithoutPerfData(t *testing.T) {
+	msg, metrics := heartbeat.ParsePluginOutput("HTTP OK\nsecond line\n")
+	assert.Equal(t, "HTTP OK", msg)
+	assert.Empty(t, metrics)
+}
+
+func TestResponseNagiosString(t *testing.T) {
+	db := heartbeat.StatusResult{Status: heartbeat.StatusCritical, Name: "db", RequestDuration: 3, Message: "connection refused\nmore detail",
+		Metrics: []heartbeat.Metric{{Label: "conns", Value: 7, Warn: "80", Crit: "95"}}}
+	db.SetTimeout(2 * time.Second)
+	resp := heartbeat.Response{
+		Status: heartbeat.StatusCritical,
+		Name:   "orders",
+		Dependencies: []heartbeat.StatusResult{
+			{Status: heartbeat.StatusOK, Name: "Payments API", RequestDuration: 12.5, Message: "ok"},
+			db,
+		},
+	}
+
+	exp := "CRITICAL - orders: 1 of 2 dependencies OK | payments_api_ms=12.5;3000;10000 db_ms=3;3000;2000 db_conns=7;80;95\n" +
+		"OK - Payments API: ok\n" +
+		"CRITICAL - db: connection refused\n"
+	assert.Equal(t, exp, resp.NagiosString())
+}
+
+func TestResponseNagiosStringReplacesPipes(t *testing.T) {
+	resp := heartbeat.Response{
+		Status:  heartbeat.StatusCritical,
+		Name:    "orders",
+		Message: "a|b",
+		Dependencies: []heartbeat.StatusResult{
+			{Status: heartbeat.StatusCritical, Name: "db|primary", Message: "exit status 2 | stderr: refused"},
+		},
+	}
+
+	exp := "CRITICAL - orders: a/b | db_primary_ms=0;3000;10000\n" +
+		"CRITICAL - db/primary: exit status 2 / stderr: refused\n"
+	assert.Equal(t, exp, resp.NagiosString())
+}
+
+func TestResponseNagiosStringWithoutDependencies(t *testing.T) {
+	resp := heartbeat.Response{Status: heartbeat.StatusNotSet, Name: "orders"}
+	assert.Equal(t, "OK - orders: no dependencies\n", resp.NagiosString())
+}
+
+func TestHandlerRenderNagios(t *testing.T) {
+	tests := []struct {
+		name         string
+		status       heartbeat.Status
+		expectedCode int
+		expectedLine string
+	}{
+		{name: "OK", status: heartbeat.StatusOK, expectedCode: http.StatusOK, expectedLine: "OK - unit-test: 1 of 1 dependencies OK"},
+		{name: "Warning", status: heartbeat.StatusWarning, expectedCode: http.StatusOK, expectedLine: "WARNING - unit-test: 0 of 1 dependencies OK"},
+		{name: "Critical", status: heartbeat.StatusCritical, expectedCode: http.StatusServiceUnavailable, expectedLine: "CRITICAL - unit-test: 0 of 1 dependencies OK"},
+		{name: "Unknown", status: heartbeat.StatusUnknown, expectedCode: http.StatusServiceUnavailable, expectedLine: "UNKNOWN - unit-test: 0 of 1 dependencies OK"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			deps := []heartbeat.DependencyDescriptor{
+				{Name: "custom", Type: "Custom", Timeout: 2 * time.Second, HandlerFunc: func() heartbeat.StatusResult {
+					return heartbeat.StatusResult{Status: tt.status}
+				}},
+			}
+
+			resp := httptest.NewRecorder()
+			gin.SetMode(gin.TestMode)
+			c, r := gin.CreateTestContext(resp)
+			r.GET("/test", heartbeat.NewHandler("unit-test", deps, heartbeat.WithRenderer(heartbeat.RenderNagios)))
+			c.Request, _ = http.NewRequest(http.MethodGet, "/test", nil)
+			r.ServeHTTP(resp, c.Request)
+
+			assert.Equal(t, tt.expectedCode, resp.Code)
+			assert.Equal(t, "text/plain; charset=utf-8", resp.Header().Get("Content-Type"))
+			assert.Regexp(t, "^"+regexp.QuoteMeta(tt.expectedLine)+` \| custom_ms=[0-9.]+;3000;2000\n`, resp.Body.String())
+		})
+	}
+}
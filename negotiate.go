@@ -0,0 +1,145 @@
+package heartbeat
+
+import (
+	"fmt"
+	"mime"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// FormatQueryParam is the query parameter that selects an Encoder by its
+// Format, taking precedence over the Accept header.
+const FormatQueryParam = "format"
+
+// Encoder is a Renderer that content negotiation can choose, either by its
+// Format in the FormatQueryParam query parameter or by one of its MediaTypes
+// in the Accept header.
+type Encoder struct {
+	Format     string
+	MediaTypes []string
+	Render     Renderer
+}
+
+// DefaultEncoders returns the encoders built into the package:
+//
+//   - json: application/json, the Response as JSON.
+//   - health: application/health+json, see RenderHealthJSON.
+//   - text: text/plain, the overall Status only, such as "OK".
+//   - yaml: application/yaml, the Response as YAML.
+//   - protobuf: application/x-protobuf, see RenderProtobuf.
+//   - nagios: selected only by format, see RenderNagios.
+func DefaultEncoders() []Encoder {
+	return []Encoder{
+		{Format: "json", MediaTypes: []string{"application/json"}, Render: RenderJSON},
+		{Format: "health", MediaTypes: []string{HealthJSONContentType}, Render: RenderHealthJSON},
+		{Format: "text", MediaTypes: []string{"text/plain"}, Render: RenderText},
+		{Format: "yaml", MediaTypes: []string{"application/yaml", "application/x-yaml", "text/yaml"}, Render: RenderYAML},
+		{Format: "protobuf", MediaTypes: []string{ProtobufContentType, "application/protobuf", "application/vnd.google.protobuf"}, Render: RenderProtobuf},
+		{Format: "nagios", Render: RenderNagios},
+	}
+}
+
+// WithContentNegotiation lets each request choose how the Response is
+// encoded, with the FormatQueryParam query parameter or the Accept header.
+// The DefaultEncoders are available along with the given ones, which replace
+// default encoders of the same Format and take precedence for their media
+// types. Requests that state no preference, or accept none of the media
+// types, get the handler's Renderer, JSON unless set with WithRenderer. An
+// unknown format is rejected with 400 Bad Request.
+func WithContentNegotiation(encoders ...Encoder) Option {
+	return func(cfg *config) {
+		var custom []Encoder
+		for _, e := range encoders {
+			if e.Render != nil {
+				custom = append(custom, e)
+			}
+		}
+		registry := slices.DeleteFunc(DefaultEncoders(), func(d Encoder) bool {
+			return slices.ContainsFunc(custom, func(e Encoder) bool { return e.Format == d.Format })
+		})
+		registry = append(custom, registry...)
+		cfg.encoders = registry
+	}
+}
+
+// negotiate returns the Renderer the request asks for.
+func (cfg *config) negotiate(c *gin.Context) (Renderer, error) {
+	if cfg.encoders == nil {
+		return cfg.renderer, nil
+	}
+	c.Header("Vary", "Accept")
+
+	if format := c.Query(FormatQueryParam); format != "" {
+		for _, e := range cfg.encoders {
+			if strings.EqualFold(e.Format, format) {
+				return e.Render, nil
+			}
+		}
+		formats := make([]string, len(cfg.encoders))
+		for i, e := range cfg.encoders {
+			formats[i] = e.Format
+		}
+		return nil, fmt.Errorf("unsupported format %q; supported formats are %s", format, strings.Join(formats, ", "))
+	}
+
+	for _, accepted := range acceptedMediaTypes(c.GetHeader("Accept")) {
+		if accepted == "*/*" {
+			break
+		}
+		for _, e := range cfg.encoders {
+			for _, mt := range e.MediaTypes {
+				if strings.EqualFold(mt, accepted) || (strings.HasSuffix(accepted, "/*") && strings.HasPrefix(mt, strings.TrimSuffix(accepted, "*"))) {
+					return e.Render, nil
+				}
+			}
+		}
+	}
+	return cfg.renderer, nil
+}
+
+// acceptedMediaTypes returns the media ranges of an Accept header, most
+// preferred first, leaving out those with a quality of 0.
+func acceptedMediaTypes(header string) []string {
+	type mediaRange struct {
+		mediaType string
+		q         float64
+	}
+	var ranges []mediaRange
+	for _, part := range strings.Split(header, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if v, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(v, 64); err != nil {
+				continue
+			}
+		}
+		if q > 0 {
+			ranges = append(ranges, mediaRange{mediaType: mediaType, q: q})
+		}
+	}
+	sort.SliceStable(ranges, func(i, j int) bool { return ranges[i].q > ranges[j].q })
+
+	types := make([]string, len(ranges))
+	for i, r := range ranges {
+		types[i] = r.mediaType
+	}
+	return types
+}
+
+// RenderText renders the overall Status as plain text, such as "OK", for load
+// balancers that match on the response body.
+func RenderText(c *gin.Context, httpStatus int, resp Response) {
+	c.Data(httpStatus, "text/plain; charset=utf-8", []byte(resp.Status.String()+"\n"))
+}
+
+// RenderYAML renders the Response as YAML, with the field names of the JSON.
+func RenderYAML(c *gin.Context, httpStatus int, resp Response) {
+	c.YAML(httpStatus, resp)
+}
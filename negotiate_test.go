@@ -0,0 +1,128 @@
+package heartbeat_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/goccy/go-yaml"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/twistingmercury/heartbeat"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// warningDeps returns a dependency that reports Warning.
+func warningDeps() []heartbeat.DependencyDescriptor {
+	return []heartbeat.DependencyDescriptor{
+		{Name: "cache", Type: "redis", HandlerFunc: func() heartbeat.StatusResult {
+			return heartbeat.StatusResult{Status: heartbeat.StatusWarning, Message: "slow"}
+		}},
+	}
+}
+
+func TestContentNegotiationChoosesEncoder(t *testing.T) {
+	r := healthHandler(warningDeps(), heartbeat.WithContentNegotiation())
+
+	tests := []struct {
+		name        string
+		target      string
+		accept      string
+		contentType string
+	}{
+		{name: "no preference", target: "/health", contentType: "application/json; charset=utf-8"},
+		{name: "any", target: "/health", accept: "*/*", contentType: "application/json; charset=utf-8"},
+		{name: "unsupported", target: "/health", accept: "application/xml", contentType: "application/json; charset=utf-8"},
+		{name: "json", target: "/health", accept: "application/json", contentType: "application/json; charset=utf-8"},
+		{name: "text", target: "/health", accept: "text/plain", contentType: "text/plain; charset=utf-8"},
+		{name: "health json", target: "/health", accept: "application/health+json", contentType: heartbeat.HealthJSONContentType},
+		{name: "yaml", target: "/health", accept: "application/x-yaml", contentType: "application/yaml; charset=utf-8"},
+		{name: "protobuf", target: "/health", accept: "application/x-protobuf", contentType: heartbeat.ProtobufContentType},
+		{name: "quality", target: "/health", accept: "application/json;q=0.5, text/plain", contentType: "text/plain; charset=utf-8"},
+		{name: "quality zero", target: "/health", accept: "text/plain;q=0, application/yaml;q=0.1", contentType: "application/yaml; charset=utf-8"},
+		{name: "wildcard subtype", target: "/health", accept: "text/*", contentType: "text/plain; charset=utf-8"},
+		{name: "format", target: "/health?format=text", accept: "application/json", contentType: "text/plain; charset=utf-8"},
+		{name: "nagios format", target: "/health?format=nagios", contentType: "text/plain; charset=utf-8"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := serveHealth(r, context.Background(), withTarget(tt.target), withHeader("Accept", tt.accept))
+			assert.Equal(t, http.StatusOK, resp.Code)
+			assert.Equal(t, tt.contentType, resp.Header().Get("Content-Type"))
+			assert.Equal(t, "Accept", resp.Header().Get("Vary"))
+		})
+	}
+}
+
+func TestContentNegotiationEncodings(t *testing.T) {
+	r := healthHandler(warningDeps(), heartbeat.WithContentNegotiation())
+
+	assert.Equal(t, "Warning\n", serveHealth(r, context.Background(), withTarget("/health?format=text")).Body.String())
+
+	var fromYAML map[string]any
+	require.NoError(t, yaml.Unmarshal(serveHealth(r, context.Background(), withTarget("/health?format=yaml")).Body.Bytes(), &fromYAML))
+	assert.Equal(t, "Warning", fromYAML["status"])
+	assert.Equal(t, "unit-test", fromYAML["name"])
+	assert.Contains(t, fromYAML, "request_duration_ms")
+
+	msg := dynamicpb.NewMessage(heartbeat.ProtoResponseDescriptor())
+	require.NoError(t, proto.Unmarshal(serveHealth(r, context.Background(), withTarget("/health?format=protobuf")).Body.Bytes(), msg))
+	fields := msg.Descriptor().Fields()
+	assert.Equal(t, int32(heartbeat.StatusWarning), int32(msg.Get(fields.ByName("status")).Enum()))
+	assert.Equal(t, "unit-test", msg.Get(fields.ByName("name")).String())
+	assert.NotZero(t, msg.Get(fields.ByName("utc_date_time_unix_nano")).Int())
+
+	deps := msg.Get(fields.ByName("dependencies")).List()
+	require.Equal(t, 1, deps.Len())
+	dep := deps.Get(0).Message()
+	depFields := dep.Descriptor().Fields()
+	assert.Equal(t, "cache", dep.Get(depFields.ByName("name")).String())
+	assert.Equal(t, "redis", dep.Get(depFields.ByName("type")).String())
+	assert.Equal(t, "slow", dep.Get(depFields.ByName("message")).String())
+}
+
+func TestContentNegotiationRejectsUnknownFormat(t *testing.T) {
+	r := healthHandler(warningDeps(), heartbeat.WithContentNegotiation())
+	resp := serveHealth(r, context.Background(), withTarget("/health?format=xml"))
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+
+	var body map[string]string
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &body))
+	assert.Contains(t, body["message"], `unsupported format "xml"`)
+}
+
+func TestContentNegotiationCustomEncoders(t *testing.T) {
+	csv := heartbeat.Encoder{
+		Format:     "csv",
+		MediaTypes: []string{"text/csv"},
+		Render: func(c *gin.Context, httpStatus int, resp heartbeat.Response) {
+			c.Data(httpStatus, "text/csv", []byte("name,status\n"+resp.Name+","+resp.Status.String()+"\n"))
+		},
+	}
+	text := heartbeat.Encoder{
+		Format:     "text",
+		MediaTypes: []string{"text/plain"},
+		Render: func(c *gin.Context, httpStatus int, resp heartbeat.Response) {
+			c.String(httpStatus, "custom")
+		},
+	}
+	r := healthHandler(warningDeps(), heartbeat.WithContentNegotiation(csv, text), heartbeat.WithRenderer(heartbeat.RenderNagios))
+
+	assert.Equal(t, "name,status\nunit-test,Warning\n", serveHealth(r, context.Background(), withHeader("Accept", "text/csv")).Body.String())
+	assert.Equal(t, "custom", serveHealth(r, context.Background(), withTarget("/health?format=text")).Body.String())
+	assert.Equal(t, "custom", serveHealth(r, context.Background(), withHeader("Accept", "text/plain")).Body.String())
+
+	// Without a preference, the handler's renderer is used.
+	assert.Contains(t, serveHealth(r, context.Background()).Body.String(), "WARNING - unit-test")
+}
+
+func TestWithoutContentNegotiationAcceptIsIgnored(t *testing.T) {
+	r := healthHandler(warningDeps())
+	resp := serveHealth(r, context.Background(), withTarget("/health?format=text"), withHeader("Accept", "text/plain"))
+	assert.Equal(t, "application/json; charset=utf-8", resp.Header().Get("Content-Type"))
+	assert.Empty(t, resp.Header().Get("Vary"))
+}
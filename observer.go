@@ -0,0 +1,40 @@
+package heartbeat
+
+import "time"
+
+// CheckRun is the outcome of one run of a handler's dependency checks.
+type CheckRun struct {
+	Service  string
+	Status   Status
+	Time     time.Time // when the run completed
+	Duration time.Duration
+	Results  []StatusResult
+}
+
+// Observer receives every completed run of a handler's dependency checks. When
+// requests are coalesced, a shared run is observed once. Runs cut short
+// by the caller, because it went away or its TimeoutQueryParam passed, are not
+// observed.
+//
+// ObserveRun is called synchronously before the response is written, so it
+// must be quick and safe for concurrent use, and it must not modify the run.
+type Observer interface {
+	ObserveRun(run CheckRun)
+}
+
+// ObserverFunc adapts an ordinary function to the Observer interface.
+type ObserverFunc func(run CheckRun)
+
+// ObserveRun calls f(run).
+func (f ObserverFunc) ObserveRun(run CheckRun) {
+	f(run)
+}
+
+// WithObserver adds an Observer of the handler's check runs.
+func WithObserver(o Observer) Option {
+	return func(cfg *config) {
+		if o != nil {
+			cfg.observers = append(cfg.observers, o)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package heartbeat
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Option configures a handler created by NewHandler.
+type Option func(*config)
+
+type config struct {
+	renderer      Renderer
+	statusCodes   StatusCodes
+	headers       []ResponseHeaders
+	encoders      []Encoder // nil without content negotiation
+	coalesce      bool
+	coalesceReuse time.Duration
+
+	maxConcurrency int
+	deadline       time.Duration
+
+	observers []Observer
+	tracer    trace.Tracer
+
+	history        *History
+	historySummary int
+	uptime         *Uptime
+
+	authorizers []Authorizer // nil when every caller gets full detail
+	redactor    *Redactor    // nil without redaction
+}
+
+func newConfig(opts []Option) *config {
+	cfg := &config{
+		renderer:    RenderJSON,
+		statusCodes: DefaultStatusCodes(),
+		redactor:    NewRedactor(RedactionOptions{}),
+	}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	return cfg
+}
+
+// Renderer writes the Response to the caller using the given HTTP status code.
+type Renderer func(c *gin.Context, httpStatus int, resp Response)
+
+// RenderJSON renders the Response as JSON. It is the default Renderer.
+func RenderJSON(c *gin.Context, httpStatus int, resp Response) {
+	c.JSON(httpStatus, resp)
+}
+
+// WithRenderer sets the Renderer used to write the Response.
+func WithRenderer(r Renderer) Option {
+	return func(cfg *config) {
+		if r != nil {
+			cfg.renderer = r
+		}
+	}
+}
+
+// WithCoalescing makes concurrent health requests share a single in-flight run
+// of the dependency checks, so the number of check executions doesn't grow with
+// the number of probers. A completed run is also reused by requests arriving
+// within the reuse window; pass 0 to only share in-flight runs.
+func WithCoalescing(reuse time.Duration) Option {
+	return func(cfg *config) {
+		cfg.coalesce = true
+		cfg.coalesceReuse = reuse
+	}
+}
+
+// WithMaxConcurrency limits the number of dependency checks the handler runs at
+// once, across all requests; a check that cannot start within its timeout is
+// reported as Critical. It also stops a dependency from starting a new check
+// while a previous one is still running after its timeout, and reports the
+// number of such abandoned checks in Response.AbandonedChecks. A limit below 1
+// only enables the abandoned check tracking.
+func WithMaxConcurrency(limit int) Option {
+	return func(cfg *config) {
+		cfg.maxConcurrency = limit
+		if limit < 1 {
+			cfg.maxConcurrency = -1
+		}
+	}
+}
+
+// WithDeadline bounds the time the handler spends on a request. Checks still
+// pending when the deadline passes are reported as timed out, so the response
+// arrives within the budget even when dependency timeouts are longer. A request
+// can shorten the deadline with the TimeoutQueryParam query parameter.
+func WithDeadline(d time.Duration) Option {
+	return func(cfg *config) {
+		cfg.deadline = d
+	}
+}
@@ -0,0 +1,143 @@
+package heartbeat
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// instrumentationName identifies the tracer and meter used by the handler.
+const instrumentationName = "github.com/twistingmercury/heartbeat"
+
+// Attribute keys set on the spans and metrics of the handler.
+const (
+	AttrService        = attribute.Key("heartbeat.service")
+	AttrStatus         = attribute.Key("heartbeat.status")
+	AttrDependencyName = attribute.Key("heartbeat.dependency.name")
+	AttrDependencyType = attribute.Key("heartbeat.dependency.type")
+	AttrHTTPStatusCode = attribute.Key("http.response.status_code")
+)
+
+// WithTracerProvider traces the handler with the given provider: a
+// "heartbeat.health" span for every request, which is a child of any span in
+// the request's context, and a "heartbeat.check" child span for every
+// dependency check. Failed checks set the span status to Error.
+func WithTracerProvider(tp trace.TracerProvider) Option {
+	return func(cfg *config) {
+		if tp != nil {
+			cfg.tracer = tp.Tracer(instrumentationName)
+		}
+	}
+}
+
+// WithMeterProvider records the results of the handler's check runs as
+// OpenTelemetry metrics with the given provider:
+//
+//   - heartbeat.health.duration, a histogram of check run durations by service
+//     and overall status.
+//   - heartbeat.check.duration, a histogram of dependency check durations by
+//     service, dependency name and type, status and HTTP status code.
+//   - heartbeat.checks, a counter of dependency checks with the same attributes.
+func WithMeterProvider(mp metric.MeterProvider) Option {
+	return func(cfg *config) {
+		if mp != nil {
+			cfg.observers = append(cfg.observers, newOtelMetrics(mp.Meter(instrumentationName)))
+		}
+	}
+}
+
+// startHealthSpan starts the span of a health request. It returns ctx and a
+// no-op end function when tracing is disabled.
+func (h *handler) startHealthSpan(ctx context.Context) (context.Context, func(Status, int)) {
+	if h.cfg.tracer == nil {
+		return ctx, func(Status, int) {}
+	}
+	ctx, span := h.cfg.tracer.Start(ctx, "heartbeat.health",
+		trace.WithAttributes(AttrService.String(h.name)))
+	return ctx, func(status Status, httpStatus int) {
+		span.SetAttributes(AttrStatus.String(status.String()), AttrHTTPStatusCode.Int(httpStatus))
+		if status.failing() {
+			span.SetStatus(codes.Error, "service is "+status.String())
+		}
+		span.End()
+	}
+}
+
+// startCheckSpan starts the span of a dependency check. It returns ctx and a
+// no-op end function when tracing is disabled.
+func (rn *runner) startCheckSpan(ctx context.Context, d DependencyDescriptor) (context.Context, func(StatusResult)) {
+	if rn.tracer == nil {
+		return ctx, func(StatusResult) {}
+	}
+	ctx, span := rn.tracer.Start(ctx, "heartbeat.check",
+		trace.WithAttributes(AttrDependencyName.String(d.Name), AttrDependencyType.String(d.Type)))
+	return ctx, func(hsr StatusResult) {
+		span.SetAttributes(AttrStatus.String(hsr.Status.String()))
+		if hsr.StatusCode != 0 {
+			span.SetAttributes(AttrHTTPStatusCode.Int(hsr.StatusCode))
+		}
+		if hsr.Status.failing() {
+			span.SetStatus(codes.Error, hsr.Message)
+		}
+		span.End()
+	}
+}
+
+// otelMetrics is an Observer that records check runs as OpenTelemetry metrics.
+type otelMetrics struct {
+	runDuration   metric.Float64Histogram
+	checkDuration metric.Float64Histogram
+	checks        metric.Int64Counter
+}
+
+func newOtelMetrics(meter metric.Meter) *otelMetrics {
+	m := &otelMetrics{}
+	var err error
+	m.runDuration, err = meter.Float64Histogram("heartbeat.health.duration",
+		metric.WithDescription("Duration of a run of the dependency checks."),
+		metric.WithUnit("s"))
+	if err != nil {
+		otel.Handle(err)
+	}
+	m.checkDuration, err = meter.Float64Histogram("heartbeat.check.duration",
+		metric.WithDescription("Duration of dependency checks."),
+		metric.WithUnit("s"))
+	if err != nil {
+		otel.Handle(err)
+	}
+	m.checks, err = meter.Int64Counter("heartbeat.checks",
+		metric.WithDescription("Dependency checks by status."),
+		metric.WithUnit("{check}"))
+	if err != nil {
+		otel.Handle(err)
+	}
+	return m
+}
+
+// ObserveRun implements Observer.
+func (m *otelMetrics) ObserveRun(run CheckRun) {
+	ctx := context.Background()
+	m.runDuration.Record(ctx, run.Duration.Seconds(), metric.WithAttributes(
+		AttrService.String(run.Service),
+		AttrStatus.String(run.Status.String()),
+	))
+
+	for _, r := range run.Results {
+		attrs := []attribute.KeyValue{
+			AttrService.String(run.Service),
+			AttrDependencyName.String(r.Name),
+			AttrDependencyType.String(r.Type),
+			AttrStatus.String(r.Status.String()),
+		}
+		if r.StatusCode != 0 {
+			attrs = append(attrs, AttrHTTPStatusCode.Int(r.StatusCode))
+		}
+		set := metric.WithAttributeSet(attribute.NewSet(attrs...))
+		m.checkDuration.Record(ctx, r.RequestDuration/1000, set)
+		m.checks.Add(ctx, 1, set)
+	}
+}
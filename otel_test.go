@@ -0,0 +1,138 @@
+package heartbeat_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/twistingmercury/heartbeat"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func otelDeps(t *testing.T) []heartbeat.DependencyDescriptor {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	t.Cleanup(srv.Close)
+
+	return []heartbeat.DependencyDescriptor{
+		{Name: "db", Type: "database", HandlerFunc: func() heartbeat.StatusResult {
+			return heartbeat.StatusResult{Status: heartbeat.StatusOK, Message: "ok"}
+		}},
+		{Name: "api", Type: "HTTP", Connection: srv.URL},
+	}
+}
+
+func TestTracerProviderRecordsSpans(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/health", heartbeat.NewHandler("unit-test", otelDeps(t), heartbeat.WithTracerProvider(tp)))
+
+	// The handler span is a child of the span in the request context.
+	ctx, parent := tp.Tracer("test").Start(context.Background(), "request")
+	resp := serveHealth(r, ctx)
+	parent.End()
+	assert.Equal(t, http.StatusServiceUnavailable, resp.Code)
+
+	spans := map[string]tracetest.SpanStub{}
+	for _, s := range exporter.GetSpans() {
+		key := s.Name
+		for _, kv := range s.Attributes {
+			if kv.Key == heartbeat.AttrDependencyName {
+				key += "/" + kv.Value.AsString()
+			}
+		}
+		spans[key] = s
+	}
+	require.Len(t, spans, 4)
+
+	health := spans["heartbeat.health"]
+	assert.Equal(t, parent.SpanContext().SpanID(), health.Parent.SpanID())
+	assert.Equal(t, codes.Error, health.Status.Code)
+	assert.Contains(t, health.Attributes, heartbeat.AttrService.String("unit-test"))
+	assert.Contains(t, health.Attributes, heartbeat.AttrStatus.String("Critical"))
+	assert.Contains(t, health.Attributes, heartbeat.AttrHTTPStatusCode.Int(http.StatusServiceUnavailable))
+
+	db := spans["heartbeat.check/db"]
+	assert.Equal(t, health.SpanContext.SpanID(), db.Parent.SpanID())
+	assert.Equal(t, codes.Unset, db.Status.Code)
+	assert.Contains(t, db.Attributes, heartbeat.AttrDependencyType.String("database"))
+	assert.Contains(t, db.Attributes, heartbeat.AttrStatus.String("OK"))
+
+	api := spans["heartbeat.check/api"]
+	assert.Equal(t, health.SpanContext.SpanID(), api.Parent.SpanID())
+	assert.Equal(t, codes.Error, api.Status.Code)
+	assert.Contains(t, api.Attributes, heartbeat.AttrDependencyType.String("HTTP"))
+	assert.Contains(t, api.Attributes, heartbeat.AttrStatus.String("Critical"))
+	assert.Contains(t, api.Attributes, heartbeat.AttrHTTPStatusCode.Int(http.StatusInternalServerError))
+}
+
+func TestMeterProviderRecordsMetrics(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	mp := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/health", heartbeat.NewHandler("unit-test", otelDeps(t), heartbeat.WithMeterProvider(mp)))
+	serveHealth(r, context.Background())
+	serveHealth(r, context.Background())
+
+	var rm metricdata.ResourceMetrics
+	require.NoError(t, reader.Collect(context.Background(), &rm))
+	require.Len(t, rm.ScopeMetrics, 1)
+	metrics := map[string]metricdata.Metrics{}
+	for _, m := range rm.ScopeMetrics[0].Metrics {
+		metrics[m.Name] = m
+	}
+
+	checks, ok := metrics["heartbeat.checks"].Data.(metricdata.Sum[int64])
+	require.True(t, ok)
+	counts := map[string]int64{}
+	for _, dp := range checks.DataPoints {
+		name, _ := dp.Attributes.Value(heartbeat.AttrDependencyName)
+		counts[name.AsString()] = dp.Value
+		if name.AsString() == "api" {
+			assert.True(t, dp.Attributes.HasValue(heartbeat.AttrHTTPStatusCode))
+			status, _ := dp.Attributes.Value(heartbeat.AttrStatus)
+			assert.Equal(t, "Critical", status.AsString())
+		}
+	}
+	assert.Equal(t, map[string]int64{"db": 2, "api": 2}, counts)
+
+	duration, ok := metrics["heartbeat.check.duration"].Data.(metricdata.Histogram[float64])
+	require.True(t, ok)
+	assert.Len(t, duration.DataPoints, 2)
+
+	run, ok := metrics["heartbeat.health.duration"].Data.(metricdata.Histogram[float64])
+	require.True(t, ok)
+	require.Len(t, run.DataPoints, 1)
+	assert.Equal(t, uint64(2), run.DataPoints[0].Count)
+	want := attribute.NewSet(heartbeat.AttrService.String("unit-test"), heartbeat.AttrStatus.String("Critical"))
+	assert.True(t, want.Equals(&run.DataPoints[0].Attributes))
+}
+
+func TestWithoutTelemetryProvidersNothingIsRecorded(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/health", heartbeat.NewHandler("unit-test", otelDeps(t)))
+
+	ctx, parent := tp.Tracer("test").Start(context.Background(), "request")
+	serveHealth(r, ctx)
+	parent.End()
+	assert.Len(t, exporter.GetSpans(), 1)
+}
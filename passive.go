@@ -0,0 +1,177 @@
+package heartbeat
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// PassiveType is the DependencyDescriptor Type of passive checks.
+const PassiveType = "passive"
+
+// ErrUnknownCheck is returned when pinging a passive check that was never registered.
+var ErrUnknownCheck = errors.New("unknown passive check")
+
+// maxPingMessage bounds the failure message read from a ping request body.
+const maxPingMessage = 4 << 10
+
+// PassiveChecks is a dead-man's switch for dependencies that cannot be polled,
+// such as cron jobs and queue consumers. Each registered job is expected to
+// check in on every run by calling Ping or Fail, or through PingHandler.
+type PassiveChecks struct {
+	mu   sync.Mutex
+	jobs map[string]*passiveJob
+	now  func() time.Time
+}
+
+type passiveJob struct {
+	interval   time.Duration
+	grace      time.Duration
+	registered time.Time
+	lastPing   time.Time
+	failed     bool
+	message    string
+}
+
+// NewPassiveChecks returns an empty set of passive checks.
+func NewPassiveChecks() *PassiveChecks {
+	return &PassiveChecks{
+		jobs: make(map[string]*passiveJob),
+		now:  time.Now,
+	}
+}
+
+// Register adds a passive check expected to ping at least once every interval,
+// and returns the DependencyDescriptor to pass to the handler. The dependency is
+// Warning once a ping is overdue and Critical once it is overdue by more than
+// grace. Registering an existing name replaces its schedule but keeps its state.
+func (p *PassiveChecks) Register(name string, interval, grace time.Duration) DependencyDescriptor {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	job, ok := p.jobs[name]
+	if !ok {
+		job = &passiveJob{registered: p.now()}
+		p.jobs[name] = job
+	}
+	job.interval = interval
+	job.grace = grace
+
+	return DependencyDescriptor{
+		Name:        name,
+		Type:        PassiveType,
+		HandlerFunc: func() StatusResult { return p.status(name) },
+	}
+}
+
+// Ping records a successful run of the named job.
+func (p *PassiveChecks) Ping(name string) error {
+	return p.record(name, false, "")
+}
+
+// Fail records a failed run of the named job. The dependency is Critical with
+// the given message until the next successful ping.
+func (p *PassiveChecks) Fail(name, message string) error {
+	return p.record(name, true, message)
+}
+
+func (p *PassiveChecks) record(name string, failed bool, message string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	job, ok := p.jobs[name]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownCheck, name)
+	}
+	job.lastPing = p.now()
+	job.failed = failed
+	job.message = message
+	return nil
+}
+
+func (p *PassiveChecks) status(name string) StatusResult {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	hsr := StatusResult{Resource: PassiveType}
+	job, ok := p.jobs[name]
+	if !ok {
+		hsr.Status = StatusCritical
+		hsr.Message = fmt.Sprintf("%v: %s", ErrUnknownCheck, name)
+		return hsr
+	}
+
+	now := p.now()
+	switch {
+	case job.failed:
+		hsr.Status = StatusCritical
+		hsr.Message = "last run failed"
+		if job.message != "" {
+			hsr.Message += ": " + job.message
+		}
+	case job.lastPing.IsZero():
+		overdue := now.Sub(job.registered) - job.interval
+		hsr.Status = overdueStatus(overdue, job.grace)
+		if overdue > 0 {
+			hsr.Message = fmt.Sprintf("no ping received, overdue by %v", overdue.Round(time.Millisecond))
+		} else {
+			hsr.Message = "awaiting first ping"
+		}
+	default:
+		since := now.Sub(job.lastPing)
+		overdue := since - job.interval
+		hsr.Status = overdueStatus(overdue, job.grace)
+		if overdue > 0 {
+			hsr.Message = fmt.Sprintf("last ping %v ago, overdue by %v", since.Round(time.Millisecond), overdue.Round(time.Millisecond))
+		} else {
+			hsr.Message = fmt.Sprintf("last ping %v ago", since.Round(time.Millisecond))
+		}
+	}
+	return hsr
+}
+
+func overdueStatus(overdue, grace time.Duration) Status {
+	switch {
+	case overdue <= 0:
+		return StatusOK
+	case overdue <= grace:
+		return StatusWarning
+	default:
+		return StatusCritical
+	}
+}
+
+// PingHandler returns a handler that records a ping for the job named by the
+// ":name" route parameter. A "status=fail" query parameter records a failed run,
+// with the message taken from the "message" query parameter or the request body.
+//
+//	r.POST("/ping/:name", passive.PingHandler())
+func (p *PassiveChecks) PingHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		name := c.Param("name")
+
+		var err error
+		if strings.EqualFold(c.Query("status"), "fail") {
+			message := c.Query("message")
+			if message == "" && c.Request.Body != nil {
+				body, _ := io.ReadAll(io.LimitReader(c.Request.Body, maxPingMessage)) // Error intentionally ignored - message is optional
+				message = strings.TrimSpace(string(body))
+			}
+			err = p.Fail(name, message)
+		} else {
+			err = p.Ping(name)
+		}
+
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"message": err.Error()})
+			return
+		}
+		c.Status(http.StatusNoContent)
+	}
+}
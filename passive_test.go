@@ -0,0 +1,118 @@
+package heartbeat_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/twistingmercury/heartbeat"
+)
+
+type fakeClock struct{ t time.Time }
+
+func (f *fakeClock) Now() time.Time          { return f.t }
+func (f *fakeClock) Advance(d time.Duration) { f.t = f.t.Add(d) }
+
+func newPassiveChecks() (*heartbeat.PassiveChecks, *fakeClock) {
+	clock := &fakeClock{t: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)}
+	p := heartbeat.NewPassiveChecks()
+	p.SetClock(clock.Now)
+	return p, clock
+}
+
+func TestPassiveChecksRegister(t *testing.T) {
+	p, _ := newPassiveChecks()
+	dep := p.Register("nightly-report", time.Hour, 10*time.Minute)
+
+	assert.Equal(t, "nightly-report", dep.Name)
+	assert.Equal(t, heartbeat.PassiveType, dep.Type)
+	assert.NotNil(t, dep.HandlerFunc)
+}
+
+func TestPassiveChecksStatusTransitions(t *testing.T) {
+	p, clock := newPassiveChecks()
+	dep := p.Register("worker", time.Minute, 30*time.Second)
+
+	result := dep.HandlerFunc()
+	assert.Equal(t, heartbeat.StatusOK, result.Status)
+	assert.Equal(t, "awaiting first ping", result.Message)
+
+	clock.Advance(80 * time.Second)
+	result = dep.HandlerFunc()
+	assert.Equal(t, heartbeat.StatusWarning, result.Status)
+	assert.Contains(t, result.Message, "no ping received")
+
+	assert.NoError(t, p.Ping("worker"))
+	clock.Advance(30 * time.Second)
+	result = dep.HandlerFunc()
+	assert.Equal(t, heartbeat.StatusOK, result.Status)
+	assert.Equal(t, "last ping 30s ago", result.Message)
+
+	clock.Advance(45 * time.Second)
+	assert.Equal(t, heartbeat.StatusWarning, dep.HandlerFunc().Status)
+
+	clock.Advance(30 * time.Second)
+	result = dep.HandlerFunc()
+	assert.Equal(t, heartbeat.StatusCritical, result.Status)
+	assert.Contains(t, result.Message, "overdue by 45s")
+}
+
+func TestPassiveChecksFail(t *testing.T) {
+	p, _ := newPassiveChecks()
+	dep := p.Register("importer", time.Minute, time.Minute)
+
+	assert.NoError(t, p.Fail("importer", "upstream file missing"))
+	result := dep.HandlerFunc()
+	assert.Equal(t, heartbeat.StatusCritical, result.Status)
+	assert.Equal(t, "last run failed: upstream file missing", result.Message)
+
+	assert.NoError(t, p.Ping("importer"))
+	assert.Equal(t, heartbeat.StatusOK, dep.HandlerFunc().Status)
+}
+
+func TestPassiveChecksUnknownName(t *testing.T) {
+	p, _ := newPassiveChecks()
+
+	assert.ErrorIs(t, p.Ping("nope"), heartbeat.ErrUnknownCheck)
+	assert.ErrorIs(t, p.Fail("nope", "x"), heartbeat.ErrUnknownCheck)
+}
+
+func TestPassiveChecksPingHandler(t *testing.T) {
+	p, _ := newPassiveChecks()
+	dep := p.Register("cron", time.Minute, time.Minute)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/ping/:name", p.PingHandler())
+
+	tests := []struct {
+		name           string
+		target         string
+		body           string
+		expectedCode   int
+		expectedStatus heartbeat.Status
+		expectedMsg    string
+	}{
+		{name: "failure with body", target: "/ping/cron?status=fail", body: "exit code 1", expectedCode: http.StatusNoContent, expectedStatus: heartbeat.StatusCritical, expectedMsg: "last run failed: exit code 1"},
+		{name: "failure with query message", target: "/ping/cron?status=fail&message=disk+full", expectedCode: http.StatusNoContent, expectedStatus: heartbeat.StatusCritical, expectedMsg: "last run failed: disk full"},
+		{name: "success", target: "/ping/cron", expectedCode: http.StatusNoContent, expectedStatus: heartbeat.StatusOK, expectedMsg: "last ping 0s ago"},
+		{name: "unknown job", target: "/ping/other", expectedCode: http.StatusNotFound, expectedStatus: heartbeat.StatusOK, expectedMsg: "last ping 0s ago"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := httptest.NewRecorder()
+			req, _ := http.NewRequest(http.MethodPost, tt.target, strings.NewReader(tt.body))
+			r.ServeHTTP(resp, req)
+
+			assert.Equal(t, tt.expectedCode, resp.Code)
+			result := dep.HandlerFunc()
+			assert.Equal(t, tt.expectedStatus, result.Status)
+			assert.Equal(t, tt.expectedMsg, result.Message)
+		})
+	}
+}
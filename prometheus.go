@@ -0,0 +1,103 @@
+package heartbeat
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// statuses lists every Status, in order, for state-set metrics.
+var statuses = []Status{StatusNotSet, StatusOK, StatusWarning, StatusUnknown, StatusCritical}
+
+// PrometheusCollector exports dependency health as Prometheus metrics. It is
+// fed by the results of the handler's check runs, so scraping never triggers a
+// check. Pass it to the handler with WithObserver and register it with a
+// prometheus.Registerer:
+//
+//	metrics := heartbeat.NewPrometheusCollector()
+//	prometheus.MustRegister(metrics)
+//	r.GET("/health", heartbeat.NewHandler("orders", deps, heartbeat.WithObserver(metrics)))
+//
+// All metrics carry the service label, and the dependency metrics also carry
+// the name and type labels of the dependency.
+type PrometheusCollector struct {
+	serviceStatus *prometheus.GaugeVec
+	status        *prometheus.GaugeVec
+	duration      *prometheus.HistogramVec
+	checks        *prometheus.CounterVec
+	lastSuccess   *prometheus.GaugeVec
+}
+
+// NewPrometheusCollector returns a collector with metrics in the "heartbeat" namespace.
+func NewPrometheusCollector() *PrometheusCollector {
+	depLabels := []string{"service", "name", "type"}
+	return &PrometheusCollector{
+		serviceStatus: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: "heartbeat",
+			Name:      "status",
+			Help:      "Overall health status of the service; 1 for the current status, 0 otherwise.",
+		}, []string{"service", "status"}),
+		status: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: "heartbeat",
+			Name:      "dependency_status",
+			Help:      "Health status of the dependency; 1 for the current status, 0 otherwise.",
+		}, append(depLabels, "status")),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: "heartbeat",
+			Name:      "dependency_check_duration_seconds",
+			Help:      "Duration of dependency checks.",
+			Buckets:   prometheus.DefBuckets,
+		}, depLabels),
+		checks: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "heartbeat",
+			Name:      "dependency_checks_total",
+			Help:      "Dependency checks by outcome status.",
+		}, append(depLabels, "outcome")),
+		lastSuccess: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: "heartbeat",
+			Name:      "dependency_last_success_timestamp_seconds",
+			Help:      "Unix time of the last check with an OK or Warning status.",
+		}, depLabels),
+	}
+}
+
+// Describe implements prometheus.Collector.
+func (p *PrometheusCollector) Describe(ch chan<- *prometheus.Desc) {
+	p.serviceStatus.Describe(ch)
+	p.status.Describe(ch)
+	p.duration.Describe(ch)
+	p.checks.Describe(ch)
+	p.lastSuccess.Describe(ch)
+}
+
+// Collect implements prometheus.Collector.
+func (p *PrometheusCollector) Collect(ch chan<- prometheus.Metric) {
+	p.serviceStatus.Collect(ch)
+	p.status.Collect(ch)
+	p.duration.Collect(ch)
+	p.checks.Collect(ch)
+	p.lastSuccess.Collect(ch)
+}
+
+// ObserveRun implements Observer.
+func (p *PrometheusCollector) ObserveRun(run CheckRun) {
+	for _, s := range statuses {
+		p.serviceStatus.WithLabelValues(run.Service, s.String()).Set(boolToFloat(s == run.Status))
+	}
+
+	for _, r := range run.Results {
+		for _, s := range statuses {
+			p.status.WithLabelValues(run.Service, r.Name, r.Type, s.String()).Set(boolToFloat(s == r.Status))
+		}
+		p.duration.WithLabelValues(run.Service, r.Name, r.Type).Observe(r.RequestDuration / 1000)
+		p.checks.WithLabelValues(run.Service, r.Name, r.Type, r.Status.String()).Inc()
+		if r.Status == StatusOK || r.Status == StatusWarning {
+			p.lastSuccess.WithLabelValues(run.Service, r.Name, r.Type).Set(float64(run.Time.UnixNano()) / 1e9)
+		}
+	}
+}
+
+func boolToFloat(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,106 @@
+package heartbeat_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/twistingmercury/heartbeat"
+)
+
+func TestPrometheusCollectorObserveRun(t *testing.T) {
+	metrics := heartbeat.NewPrometheusCollector()
+	run := heartbeat.CheckRun{
+		Service: "orders",
+		Status:  heartbeat.StatusCritical,
+		Time:    time.Unix(1700000000, 0),
+		Results: []heartbeat.StatusResult{
+			{Name: "db", Type: "database", Status: heartbeat.StatusOK, RequestDuration: 12},
+			{Name: "api", Type: "HTTP", Status: heartbeat.StatusCritical, RequestDuration: 2500},
+		},
+	}
+	metrics.ObserveRun(run)
+	metrics.ObserveRun(run)
+
+	exp := `
+# HELP heartbeat_dependency_checks_total Dependency checks by outcome status.
+# TYPE heartbeat_dependency_checks_total counter
+heartbeat_dependency_checks_total{name="api",outcome="Critical",service="orders",type="HTTP"} 2
+heartbeat_dependency_checks_total{name="db",outcome="OK",service="orders",type="database"} 2
+# HELP heartbeat_dependency_last_success_timestamp_seconds Unix time of the last check with an OK or Warning status.
+# TYPE heartbeat_dependency_last_success_timestamp_seconds gauge
+heartbeat_dependency_last_success_timestamp_seconds{name="db",service="orders",type="database"} 1.7e+09
+`
+	err := testutil.CollectAndCompare(metrics, strings.NewReader(exp),
+		"heartbeat_dependency_checks_total", "heartbeat_dependency_last_success_timestamp_seconds")
+	assert.NoError(t, err)
+
+	exp = `
+# HELP heartbeat_status Overall health status of the service; 1 for the current status, 0 otherwise.
+# TYPE heartbeat_status gauge
+heartbeat_status{service="orders",status="Critical"} 1
+heartbeat_status{service="orders",status="NotSet"} 0
+heartbeat_status{service="orders",status="OK"} 0
+heartbeat_status{service="orders",status="Unknown"} 0
+heartbeat_status{service="orders",status="Warning"} 0
+`
+	err = testutil.CollectAndCompare(metrics, strings.NewReader(exp), "heartbeat_status")
+	assert.NoError(t, err)
+
+	// 2 dependencies x 5 statuses
+	assert.Equal(t, 10, testutil.CollectAndCount(metrics, "heartbeat_dependency_status"))
+	assert.Equal(t, 2, testutil.CollectAndCount(metrics, "heartbeat_dependency_check_duration_seconds"))
+}
+
+func TestPrometheusCollectorFedByHandler(t *testing.T) {
+	deps, calls := countingDeps(0)
+	deps[0].Type = "custom"
+	metrics := heartbeat.NewPrometheusCollector()
+	reg := prometheus.NewPedanticRegistry()
+	require.NoError(t, reg.Register(metrics))
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/health", heartbeat.NewHandler("unit-test", deps, heartbeat.WithObserver(metrics)))
+	healthResponse(t, r)
+	healthResponse(t, r)
+
+	exp := `
+# HELP heartbeat_dependency_status Health status of the dependency; 1 for the current status, 0 otherwise.
+# TYPE heartbeat_dependency_status gauge
+heartbeat_dependency_status{name="slow",service="unit-test",status="Critical",type="custom"} 0
+heartbeat_dependency_status{name="slow",service="unit-test",status="NotSet",type="custom"} 0
+heartbeat_dependency_status{name="slow",service="unit-test",status="OK",type="custom"} 1
+heartbeat_dependency_status{name="slow",service="unit-test",status="Unknown",type="custom"} 0
+heartbeat_dependency_status{name="slow",service="unit-test",status="Warning",type="custom"} 0
+`
+	assert.NoError(t, testutil.GatherAndCompare(reg, strings.NewReader(exp), "heartbeat_dependency_status"))
+
+	// Gathering doesn't run any checks.
+	assert.Equal(t, int32(2), calls.Load())
+}
+
+func TestObserverSkipsCancelledRuns(t *testing.T) {
+	deps, _ := countingDeps(100 * time.Millisecond)
+	observed := 0
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/health", heartbeat.NewHandler("unit-test", deps, heartbeat.WithObserver(
+		heartbeat.ObserverFunc(func(run heartbeat.CheckRun) { observed++ }))))
+
+	// The prober gives up before the check completes.
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	serveHealth(r, ctx)
+	assert.Equal(t, 0, observed)
+
+	healthResponse(t, r)
+	assert.Equal(t, 1, observed)
+}
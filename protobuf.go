@@ -0,0 +1,124 @@
+package heartbeat
+
+import (
+	"net/http"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"google.golang.org/protobuf/encoding/prototext"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// ProtobufContentType is the media type of protobuf encoded responses.
+const ProtobufContentType = "application/x-protobuf"
+
+// protoSchema is proto/heartbeat/v1/heartbeat.proto as a FileDescriptorProto,
+// so that responses can be encoded without generated code. Keep the two in
+// sync; TestProtoSchemaMatchesProtoFile compares them.
+const protoSchema = `
+name: "heartbeat/v1/heartbeat.proto"
+package: "heartbeat.v1"
+syntax: "proto3"
+enum_type {
+  name: "Status"
+  value { name: "STATUS_NOT_SET" number: 0 }
+  value { name: "STATUS_OK" number: 1 }
+  value { name: "STATUS_WARNING" number: 2 }
+  value { name: "STATUS_CRITICAL" number: 3 }
+  value { name: "STATUS_UNKNOWN" number: 4 }
+}
+message_type {
+  name: "Response"
+  field { name: "status" number: 1 label: LABEL_OPTIONAL type: TYPE_ENUM type_name: ".heartbeat.v1.Status" json_name: "status" }
+  field { name: "name" number: 2 label: LABEL_OPTIONAL type: TYPE_STRING json_name: "name" }
+  field { name: "resource" number: 3 label: LABEL_OPTIONAL type: TYPE_STRING json_name: "resource" }
+  field { name: "machine" number: 4 label: LABEL_OPTIONAL type: TYPE_STRING json_name: "machine" }
+  field { name: "utc_date_time_unix_nano" number: 5 label: LABEL_OPTIONAL type: TYPE_INT64 json_name: "utcDateTimeUnixNano" }
+  field { name: "request_duration_ms" number: 6 label: LABEL_OPTIONAL type: TYPE_DOUBLE json_name: "requestDurationMs" }
+  field { name: "message" number: 7 label: LABEL_OPTIONAL type: TYPE_STRING json_name: "message" }
+  field { name: "abandoned_checks" number: 8 label: LABEL_OPTIONAL type: TYPE_INT32 json_name: "abandonedChecks" }
+  field { name: "dependencies" number: 9 label: LABEL_REPEATED type: TYPE_MESSAGE type_name: ".heartbeat.v1.Dependency" json_name: "dependencies" }
+}
+message_type {
+  name: "Dependency"
+  field { name: "status" number: 1 label: LABEL_OPTIONAL type: TYPE_ENUM type_name: ".heartbeat.v1.Status" json_name: "status" }
+  field { name: "name" number: 2 label: LABEL_OPTIONAL type: TYPE_STRING json_name: "name" }
+  field { name: "type" number: 3 label: LABEL_OPTIONAL type: TYPE_STRING json_name: "type" }
+  field { name: "resource" number: 4 label: LABEL_OPTIONAL type: TYPE_STRING json_name: "resource" }
+  field { name: "request_duration_ms" number: 5 label: LABEL_OPTIONAL type: TYPE_DOUBLE json_name: "requestDurationMs" }
+  field { name: "http_status_code" number: 6 label: LABEL_OPTIONAL type: TYPE_INT32 json_name: "httpStatusCode" }
+  field { name: "message" number: 7 label: LABEL_OPTIONAL type: TYPE_STRING json_name: "message" }
+  field { name: "attempts" number: 8 label: LABEL_OPTIONAL type: TYPE_INT32 json_name: "attempts" }
+  field { name: "circuit_open" number: 9 label: LABEL_OPTIONAL type: TYPE_BOOL json_name: "circuitOpen" }
+  field { name: "auth_failed" number: 10 label: LABEL_OPTIONAL type: TYPE_BOOL json_name: "authFailed" }
+}
+`
+
+var protoFile = sync.OnceValue(func() protoreflect.FileDescriptor {
+	var fdp descriptorpb.FileDescriptorProto
+	if err := prototext.Unmarshal([]byte(protoSchema), &fdp); err != nil {
+		panic("heartbeat: invalid protobuf schema: " + err.Error())
+	}
+	fd, err := protodesc.NewFile(&fdp, protoregistry.GlobalFiles)
+	if err != nil {
+		panic("heartbeat: invalid protobuf schema: " + err.Error())
+	}
+	return fd
+})
+
+// ProtoResponseDescriptor describes the heartbeat.v1.Response message of
+// protobuf encoded responses, for decoding them with dynamicpb. The schema is
+// also available as proto/heartbeat/v1/heartbeat.proto.
+func ProtoResponseDescriptor() protoreflect.MessageDescriptor {
+	return protoFile().Messages().ByName("Response")
+}
+
+// RenderProtobuf renders the Response as a heartbeat.v1.Response protobuf message.
+func RenderProtobuf(c *gin.Context, httpStatus int, resp Response) {
+	body, err := proto.Marshal(resp.protoMessage())
+	if err != nil {
+		_ = c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+	c.Data(httpStatus, ProtobufContentType, body)
+}
+
+func (h *Response) protoMessage() proto.Message {
+	md := ProtoResponseDescriptor()
+	msg := dynamicpb.NewMessage(md)
+	fields := md.Fields()
+	msg.Set(fields.ByName("status"), protoreflect.ValueOfEnum(protoreflect.EnumNumber(h.Status)))
+	msg.Set(fields.ByName("name"), protoreflect.ValueOfString(h.Name))
+	msg.Set(fields.ByName("resource"), protoreflect.ValueOfString(h.Resource))
+	msg.Set(fields.ByName("machine"), protoreflect.ValueOfString(h.Machine))
+	if !h.UtcDateTime.IsZero() {
+		msg.Set(fields.ByName("utc_date_time_unix_nano"), protoreflect.ValueOfInt64(h.UtcDateTime.UnixNano()))
+	}
+	msg.Set(fields.ByName("request_duration_ms"), protoreflect.ValueOfFloat64(h.RequestDuration))
+	msg.Set(fields.ByName("message"), protoreflect.ValueOfString(h.Message))
+	msg.Set(fields.ByName("abandoned_checks"), protoreflect.ValueOfInt32(int32(h.AbandonedChecks)))
+
+	depField := fields.ByName("dependencies")
+	list := msg.Mutable(depField).List()
+	for _, d := range h.Dependencies {
+		dep := list.NewElement().Message()
+		df := dep.Descriptor().Fields()
+		dep.Set(df.ByName("status"), protoreflect.ValueOfEnum(protoreflect.EnumNumber(d.Status)))
+		dep.Set(df.ByName("name"), protoreflect.ValueOfString(d.Name))
+		dep.Set(df.ByName("type"), protoreflect.ValueOfString(d.Type))
+		dep.Set(df.ByName("resource"), protoreflect.ValueOfString(d.Resource))
+		dep.Set(df.ByName("request_duration_ms"), protoreflect.ValueOfFloat64(d.RequestDuration))
+		dep.Set(df.ByName("http_status_code"), protoreflect.ValueOfInt32(int32(d.StatusCode)))
+		dep.Set(df.ByName("message"), protoreflect.ValueOfString(d.Message))
+		dep.Set(df.ByName("attempts"), protoreflect.ValueOfInt32(int32(d.Attempts)))
+		dep.Set(df.ByName("circuit_open"), protoreflect.ValueOfBool(d.CircuitOpen))
+		dep.Set(df.ByName("auth_failed"), protoreflect.ValueOfBool(d.AuthFailed))
+		list.Append(protoreflect.ValueOfMessage(dep))
+	}
+	return msg
+}
@@ -0,0 +1,142 @@
+package heartbeat_test
+
+import (
+	"bufio"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/twistingmercury/heartbeat"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// protoField is a field of a message declared in a .proto file.
+type protoField struct {
+	typ      string
+	number   int
+	repeated bool
+}
+
+// protoFile is what parseProto reads from a .proto file.
+type protoFile struct {
+	pkg      string
+	enums    map[string]map[string]int
+	messages map[string]map[string]protoField
+}
+
+// parseProto reads the subset of the proto3 syntax used by heartbeat.proto:
+// a package, and top-level enums and messages with scalar, enum and message
+// fields, optionally repeated.
+func parseProto(t *testing.T, path string) protoFile {
+	t.Helper()
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer func() {
+		_ = f.Close() // Error intentionally ignored
+	}()
+
+	pf := protoFile{enums: map[string]map[string]int{}, messages: map[string]map[string]protoField{}}
+	var enum map[string]int
+	var message map[string]protoField
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "//")
+		line = strings.TrimSpace(line)
+		fields := strings.Fields(strings.NewReplacer("=", " = ", ";", " ").Replace(line))
+		switch {
+		case line == "":
+		case fields[0] == "syntax" || fields[0] == "option":
+		case fields[0] == "package":
+			pf.pkg = fields[1]
+		case fields[0] == "enum":
+			enum = map[string]int{}
+			pf.enums[fields[1]] = enum
+		case fields[0] == "message":
+			message = map[string]protoField{}
+			pf.messages[fields[1]] = message
+		case line == "}":
+			enum, message = nil, nil
+		case enum != nil && len(fields) == 3 && fields[1] == "=":
+			n, err := strconv.Atoi(fields[2])
+			require.NoError(t, err, line)
+			enum[fields[0]] = n
+		case message != nil:
+			var field protoField
+			if fields[0] == "repeated" {
+				field.repeated = true
+				fields = fields[1:]
+			}
+			require.Len(t, fields, 4, line)
+			n, err := strconv.Atoi(fields[3])
+			require.NoError(t, err, line)
+			field.typ, field.number = fields[0], n
+			message[fields[1]] = field
+		default:
+			t.Fatalf("unexpected line in %s: %q", path, line)
+		}
+	}
+	require.NoError(t, scanner.Err())
+	return pf
+}
+
+// protoTypeName returns the type of the field as it is written in a .proto file.
+func protoTypeName(fd protoreflect.FieldDescriptor) string {
+	switch fd.Kind() {
+	case protoreflect.EnumKind:
+		return string(fd.Enum().Name())
+	case protoreflect.MessageKind:
+		return string(fd.Message().Name())
+	default:
+		return fd.Kind().String()
+	}
+}
+
+func TestProtoSchemaMatchesProtoFile(t *testing.T) {
+	pf := parseProto(t, "proto/heartbeat/v1/heartbeat.proto")
+	fd := heartbeat.ProtoResponseDescriptor().ParentFile()
+
+	assert.Equal(t, pf.pkg, string(fd.Package()))
+
+	require.Equal(t, len(pf.enums), fd.Enums().Len())
+	for name, values := range pf.enums {
+		ed := fd.Enums().ByName(protoreflect.Name(name))
+		require.NotNil(t, ed, "enum %s", name)
+		require.Equal(t, len(values), ed.Values().Len(), "enum %s", name)
+		for value, number := range values {
+			vd := ed.Values().ByName(protoreflect.Name(value))
+			if assert.NotNil(t, vd, "%s.%s", name, value) {
+				assert.Equal(t, protoreflect.EnumNumber(number), vd.Number(), "%s.%s", name, value)
+			}
+		}
+	}
+
+	require.Equal(t, len(pf.messages), fd.Messages().Len())
+	for name, fields := range pf.messages {
+		md := fd.Messages().ByName(protoreflect.Name(name))
+		require.NotNil(t, md, "message %s", name)
+		require.Equal(t, len(fields), md.Fields().Len(), "message %s", name)
+		for field, want := range fields {
+			got := md.Fields().ByName(protoreflect.Name(field))
+			if !assert.NotNil(t, got, "%s.%s", name, field) {
+				continue
+			}
+			assert.Equal(t, protoreflect.FieldNumber(want.number), got.Number(), "%s.%s", name, field)
+			assert.Equal(t, want.typ, protoTypeName(got), "%s.%s", name, field)
+			assert.Equal(t, want.repeated, got.IsList(), "%s.%s", name, field)
+		}
+	}
+}
+
+func TestStatusMatchesProtoEnum(t *testing.T) {
+	ed := heartbeat.ProtoResponseDescriptor().Fields().ByName("status").Enum()
+	for _, s := range []heartbeat.Status{heartbeat.StatusNotSet, heartbeat.StatusOK, heartbeat.StatusWarning, heartbeat.StatusCritical, heartbeat.StatusUnknown} {
+		vd := ed.Values().ByNumber(protoreflect.EnumNumber(s))
+		if assert.NotNil(t, vd, s.String()) {
+			name := strings.ReplaceAll(strings.ToUpper("STATUS_"+s.String()), "NOTSET", "NOT_SET")
+			assert.Equal(t, name, string(vd.Name()))
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package heartbeat
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Redacted replaces credentials removed from check results.
+const Redacted = "REDACTED"
+
+// DefaultSensitiveQueryParams are the query parameters whose values are
+// redacted by default, matched case-insensitively.
+var DefaultSensitiveQueryParams = []string{
+	"access_token", "api_key", "apikey", "auth", "client_secret", "code",
+	"key", "pass", "passwd", "password", "pwd", "secret", "sig", "signature",
+	"token", "x-amz-credential", "x-amz-security-token", "x-amz-signature",
+}
+
+var (
+	// urlUserinfo matches the userinfo of a URL, up to the last "@" before
+	// the path as in net/url, so that passwords may contain "@". Quotes end
+	// the URL, as in error messages.
+	urlUserinfo = regexp.MustCompile(`(?i)\b([a-z][a-z0-9+.-]*://)([^/?#\s"'<>]*)@`)
+
+	// dsnPassword matches passwords in key/value connection strings, such as
+	// "host=db password=secret" or "Server=db;Password=secret;".
+	dsnPassword = regexp.MustCompile(`(?i)\b(?:password|pwd)\s*=\s*('[^']*'|[^\s;&]+)`)
+)
+
+// RedactionOptions configures the redaction of check results, in addition to
+// the URL userinfo, the DefaultSensitiveQueryParams and passwords in key/value
+// connection strings that are always redacted.
+type RedactionOptions struct {
+	// QueryParams are further query parameters whose values are redacted.
+	QueryParams []string
+	// Patterns are further expressions to redact. A pattern without capture
+	// groups is redacted entirely; otherwise only its capture groups are, so
+	// that `token: (\S+)` keeps the "token: " prefix.
+	Patterns []*regexp.Regexp
+}
+
+// Redactor removes credentials from strings. It is safe for concurrent use.
+type Redactor struct {
+	patterns []*regexp.Regexp
+}
+
+// NewRedactor returns a Redactor for the options.
+func NewRedactor(opts RedactionOptions) *Redactor {
+	params := make([]string, 0, len(DefaultSensitiveQueryParams)+len(opts.QueryParams))
+	for _, p := range slices.Concat(DefaultSensitiveQueryParams, opts.QueryParams) {
+		if p != "" {
+			params = append(params, regexp.QuoteMeta(p))
+		}
+	}
+	queryParams := regexp.MustCompile(`(?i)[?&;](?:` + strings.Join(params, "|") + `)=([^&;#\s"'<>]*)`)
+
+	r := &Redactor{patterns: []*regexp.Regexp{queryParams, dsnPassword}}
+	for _, p := range opts.Patterns {
+		if p != nil {
+			r.patterns = append(r.patterns, p)
+		}
+	}
+	return r
+}
+
+// Redact returns s with credentials replaced by Redacted. In URLs, a password
+// is replaced and the username kept; a username without a password is
+// replaced, as it is often a token.
+func (r *Redactor) Redact(s string) string {
+	if s == "" {
+		return s
+	}
+	s = urlUserinfo.ReplaceAllStringFunc(s, func(m string) string {
+		sub := urlUserinfo.FindStringSubmatch(m)
+		user, _, hasPassword := strings.Cut(sub[2], ":")
+		if !hasPassword {
+			return sub[1] + Redacted + "@"
+		}
+		return sub[1] + user + ":" + Redacted + "@"
+	})
+	for _, p := range r.patterns {
+		s = redactMatches(p, s)
+	}
+	return s
+}
+
+// redactMatches replaces the capture groups of each match of p in s, or the
+// whole match when p has no capture groups.
+func redactMatches(p *regexp.Regexp, s string) string {
+	matches := p.FindAllStringSubmatchIndex(s, -1)
+	if matches == nil {
+		return s
+	}
+	var b strings.Builder
+	last := 0
+	for _, m := range matches {
+		spans := m[2:]
+		if len(spans) == 0 {
+			spans = m[:2]
+		}
+		for i := 0; i < len(spans); i += 2 {
+			start, end := spans[i], spans[i+1]
+			if start < last || start == end {
+				continue // unmatched or nested group
+			}
+			b.WriteString(s[last:start])
+			b.WriteString(Redacted)
+			last = end
+		}
+	}
+	b.WriteString(s[last:])
+	return b.String()
+}
+
+// RedactResult returns the result with credentials redacted from its text
+// fields.
+func (r *Redactor) RedactResult(res StatusResult) StatusResult {
+	res.Name = r.Redact(res.Name)
+	res.Type = r.Redact(res.Type)
+	res.Resource = r.Redact(res.Resource)
+	res.Message = r.Redact(res.Message)
+	if len(res.Metrics) > 0 {
+		metrics := make([]Metric, len(res.Metrics))
+		for i, m := range res.Metrics {
+			m.Label = r.Redact(m.Label)
+			metrics[i] = m
+		}
+		res.Metrics = metrics
+	}
+	return res
+}
+
+// WithRedaction configures the redaction of credentials from check results.
+// Redaction is on by default, with the zero RedactionOptions; it applies
+// before results reach the Response and the observers.
+func WithRedaction(opts RedactionOptions) Option {
+	return func(cfg *config) {
+		cfg.redactor = NewRedactor(opts)
+	}
+}
+
+// WithoutRedaction turns off the redaction of credentials from check results.
+func WithoutRedaction() Option {
+	return func(cfg *config) {
+		cfg.redactor = nil
+	}
+}
@@ -0,0 +1,123 @@
+package heartbeat
+
+import (
+	"context"
+	"math/rand/v2"
+	"time"
+)
+
+// RetryOn is a set of failure kinds that a RetryPolicy retries.
+type RetryOn uint
+
+const (
+	// RetryConnectErrors retries HTTP requests that failed before a response
+	// was received, such as refused connections and DNS errors.
+	RetryConnectErrors RetryOn = 1 << iota
+	// RetryTimeouts retries HTTP requests that exceeded the attempt timeout.
+	RetryTimeouts
+	// RetryServerErrors retries HTTP 5xx responses.
+	RetryServerErrors
+	// RetryClientErrors retries HTTP 4xx responses.
+	RetryClientErrors
+	// RetryHandlerFailures retries custom handlers that returned StatusUnknown
+	// or StatusCritical, panicked or timed out.
+	RetryHandlerFailures
+	// RetryAuthFailures retries HTTP checks whose credentials could not be
+	// obtained, such as when the OAuth2 token endpoint is unavailable.
+	RetryAuthFailures
+
+	// DefaultRetryOn is used when a RetryPolicy does not set On.
+	DefaultRetryOn = RetryConnectErrors | RetryServerErrors | RetryHandlerFailures
+)
+
+// RetryPolicy configures retries of a failed dependency check. All attempts,
+// including the backoff between them, share the dependency's Timeout.
+type RetryPolicy struct {
+	// Attempts is the total number of attempts, including the first. Values
+	// below 2 disable retries.
+	Attempts int `json:"attempts"`
+
+	// Backoff is the delay before the second attempt. It doubles for every
+	// further attempt, up to MaxBackoff when that is set.
+	Backoff    time.Duration `json:"backoff,omitempty"`
+	MaxBackoff time.Duration `json:"max_backoff,omitempty"`
+
+	// Jitter randomizes each delay by up to this fraction (0 to 1) of its value.
+	Jitter float64 `json:"jitter,omitempty"`
+
+	// AttemptTimeout bounds a single attempt. Defaults to the time remaining
+	// in the dependency's Timeout.
+	AttemptTimeout time.Duration `json:"attempt_timeout,omitempty"`
+
+	// On selects the failure kinds to retry. Defaults to DefaultRetryOn.
+	On RetryOn `json:"on,omitempty"`
+}
+
+// delay returns the backoff before the given retry, counting from 1.
+func (p *RetryPolicy) delay(retry int) time.Duration {
+	d := p.Backoff
+	for i := 1; i < retry && (p.MaxBackoff == 0 || d < p.MaxBackoff); i++ {
+		d *= 2
+	}
+	if p.MaxBackoff > 0 && d > p.MaxBackoff {
+		d = p.MaxBackoff
+	}
+	if p.Jitter > 0 && d > 0 {
+		d += time.Duration(float64(d) * p.Jitter * (rand.Float64()*2 - 1))
+	}
+	return d
+}
+
+// checkWithRetry checks the dependency until an attempt succeeds, fails in a
+// way the policy does not retry, the attempts are used up or the dependency's
+// Timeout runs out.
+func (rn *runner) checkWithRetry(ctx context.Context, d DependencyDescriptor) StatusResult {
+	policy := d.Retry
+	retryOn := policy.On
+	if retryOn == 0 {
+		retryOn = DefaultRetryOn
+	}
+	timeout := d.Timeout
+	if timeout == 0 {
+		timeout = defaultTimeout
+	}
+
+	st := time.Now()
+	budgetCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	deadline, _ := budgetCtx.Deadline()
+
+	var hsr StatusResult
+	attempt := 1
+	for ; ; attempt++ {
+		attemptTimeout := time.Until(deadline)
+		if policy.AttemptTimeout > 0 && policy.AttemptTimeout < attemptTimeout {
+			attemptTimeout = policy.AttemptTimeout
+		}
+
+		var failure RetryOn
+		hsr, failure = rn.attemptCheck(budgetCtx, d, attemptTimeout)
+		if failure&retryOn == 0 || attempt >= policy.Attempts {
+			break
+		}
+
+		// Give up early when the backoff would not leave time for another attempt.
+		wait := policy.delay(attempt)
+		if time.Until(deadline) <= wait {
+			break
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-budgetCtx.Done():
+			timer.Stop()
+		}
+		if budgetCtx.Err() != nil {
+			break
+		}
+	}
+
+	hsr.Attempts = attempt
+	hsr.RequestDuration = float64(time.Since(st).Microseconds()) / 1000
+	return hsr
+}
@@ -0,0 +1,138 @@
+package heartbeat_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/twistingmercury/heartbeat"
+)
+
+// flakyServer fails the first n requests with the given status code.
+func flakyServer(n int32, status int) (*httptest.Server, *atomic.Int32) {
+	var calls atomic.Int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) <= n {
+			w.WriteHeader(status)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	return ts, &calls
+}
+
+func TestRetryRecoversFromServerErrors(t *testing.T) {
+	ts, calls := flakyServer(2, http.StatusBadGateway)
+	defer ts.Close()
+
+	d := heartbeat.DependencyDescriptor{
+		Name:       "flaky",
+		Connection: ts.URL,
+		Retry:      &heartbeat.RetryPolicy{Attempts: 3, Backoff: 10 * time.Millisecond},
+	}
+
+	result := heartbeat.CheckDependency(context.Background(), d)
+	assert.Equal(t, heartbeat.StatusOK, result.Status)
+	assert.Equal(t, 3, result.Attempts)
+	assert.Equal(t, int32(3), calls.Load())
+}
+
+func TestRetryGivesUpAfterAttempts(t *testing.T) {
+	ts, calls := flakyServer(10, http.StatusServiceUnavailable)
+	defer ts.Close()
+
+	d := heartbeat.DependencyDescriptor{
+		Connection: ts.URL,
+		Retry:      &heartbeat.RetryPolicy{Attempts: 3, Backoff: time.Millisecond, Jitter: 0.5},
+	}
+
+	result := heartbeat.CheckDependency(context.Background(), d)
+	assert.Equal(t, heartbeat.StatusCritical, result.Status)
+	assert.Equal(t, 503, result.StatusCode)
+	assert.Equal(t, 3, result.Attempts)
+	assert.Equal(t, int32(3), calls.Load())
+}
+
+func TestRetrySkipsFailureKindsNotSelected(t *testing.T) {
+	ts, calls := flakyServer(10, http.StatusNotFound)
+	defer ts.Close()
+
+	d := heartbeat.DependencyDescriptor{
+		Connection: ts.URL,
+		Retry:      &heartbeat.RetryPolicy{Attempts: 5, Backoff: time.Millisecond},
+	}
+
+	result := heartbeat.CheckDependency(context.Background(), d)
+	assert.Equal(t, heartbeat.StatusCritical, result.Status)
+	assert.Equal(t, 1, result.Attempts)
+	assert.Equal(t, int32(1), calls.Load())
+
+	d.Retry.On = heartbeat.RetryClientErrors
+	result = heartbeat.CheckDependency(context.Background(), d)
+	assert.Equal(t, 5, result.Attempts)
+}
+
+func TestRetryConnectErrors(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	url := ts.URL
+	ts.Close()
+
+	d := heartbeat.DependencyDescriptor{
+		Connection: url,
+		Retry:      &heartbeat.RetryPolicy{Attempts: 2, Backoff: time.Millisecond, On: heartbeat.RetryConnectErrors},
+	}
+
+	result := heartbeat.CheckDependency(context.Background(), d)
+	assert.Equal(t, heartbeat.StatusCritical, result.Status)
+	assert.Contains(t, result.Message, "HTTP request failed")
+	assert.Equal(t, 2, result.Attempts)
+}
+
+func TestRetryStaysWithinTimeout(t *testing.T) {
+	ts, calls := flakyServer(100, http.StatusInternalServerError)
+	defer ts.Close()
+
+	d := heartbeat.DependencyDescriptor{
+		Connection: ts.URL,
+		Timeout:    300 * time.Millisecond,
+		Retry:      &heartbeat.RetryPolicy{Attempts: 50, Backoff: 100 * time.Millisecond},
+	}
+
+	st := time.Now()
+	result := heartbeat.CheckDependency(context.Background(), d)
+	assert.Less(t, time.Since(st), 300*time.Millisecond)
+	assert.Equal(t, heartbeat.StatusCritical, result.Status)
+	assert.Less(t, result.Attempts, 50)
+	assert.Equal(t, int32(result.Attempts), calls.Load())
+}
+
+func TestRetryCustomHandler(t *testing.T) {
+	var calls atomic.Int32
+	d := heartbeat.DependencyDescriptor{
+		Name: "custom",
+		HandlerFunc: func() heartbeat.StatusResult {
+			if calls.Add(1) == 1 {
+				panic("boom")
+			}
+			return heartbeat.StatusResult{Status: heartbeat.StatusOK}
+		},
+		Retry: &heartbeat.RetryPolicy{Attempts: 2},
+	}
+
+	result := heartbeat.CheckDependency(context.Background(), d)
+	assert.Equal(t, heartbeat.StatusOK, result.Status)
+	assert.Equal(t, 2, result.Attempts)
+}
+
+func TestNoRetryPolicyReportsNoAttempts(t *testing.T) {
+	ts, _ := flakyServer(1, http.StatusInternalServerError)
+	defer ts.Close()
+
+	result := heartbeat.CheckDependency(context.Background(), heartbeat.DependencyDescriptor{Connection: ts.URL})
+	assert.Equal(t, heartbeat.StatusCritical, result.Status)
+	assert.Equal(t, 0, result.Attempts)
+}
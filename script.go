@@ -0,0 +1,110 @@
+package heartbeat
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+const (
+	// scriptWaitDelay bounds the wait for output pipes held open by
+	// grandchildren once a timed out command is killed.
+	scriptWaitDelay = time.Second
+
+	// defaultScriptTimeout leaves a killed command time to be reported within
+	// the default dependency timeout.
+	defaultScriptTimeout = defaultTimeout - 2*scriptWaitDelay
+)
+
+// ScriptOptions configures a check that runs a Nagios plugin compatible command.
+type ScriptOptions struct {
+	// Command is the executable to run. It is resolved using PATH.
+	Command string
+	Args    []string
+
+	// Env holds additional "KEY=value" entries added to the service's environment.
+	Env []string
+
+	// Dir is the working directory of the command. Defaults to the service's.
+	Dir string
+
+	// Timeout bounds the command's run time. Defaults to 8 seconds. It must be
+	// shorter than the DependencyDescriptor.Timeout, by at least a couple of
+	// seconds for a command that starts other processes, or the check times
+	// out before the command is killed and the command's output is lost.
+	Timeout time.Duration
+}
+
+// ScriptCheck returns a StatusHandlerFunc that runs a Nagios style check
+// command. Exit codes 0, 1 and 2 map to StatusOK, StatusWarning and
+// StatusCritical; 3 and any other code map to StatusUnknown. The first line of
+// stdout becomes the Message and perfdata after '|' is parsed into Metrics.
+func ScriptCheck(opts ScriptOptions) StatusHandlerFunc {
+	if opts.Timeout == 0 {
+		opts.Timeout = defaultScriptTimeout
+	}
+	resource := strings.TrimSpace(opts.Command + " " + strings.Join(opts.Args, " "))
+
+	return func() StatusResult {
+		hsr := StatusResult{Resource: resource}
+		st := time.Now()
+
+		ctx, cancel := context.WithTimeout(context.Background(), opts.Timeout)
+		defer cancel()
+
+		cmd := exec.CommandContext(ctx, opts.Command, opts.Args...)
+		cmd.Dir = opts.Dir
+		if len(opts.Env) > 0 {
+			cmd.Env = append(os.Environ(), opts.Env...)
+		}
+		var stdout bytes.Buffer
+		cmd.Stdout = &stdout
+		// Don't wait on pipes held open by grandchildren once the command is killed.
+		cmd.WaitDelay = scriptWaitDelay
+
+		err := cmd.Run()
+		hsr.RequestDuration = float64(time.Since(st).Microseconds()) / 1000
+
+		hsr.Message, hsr.Metrics = parsePluginOutput(stdout.String())
+
+		var exitErr *exec.ExitError
+		switch {
+		case ctx.Err() != nil:
+			hsr.Status = StatusCritical
+			hsr.Message = fmt.Sprintf("script timed out after %v", opts.Timeout)
+			return hsr
+		case err == nil:
+			hsr.Status = StatusOK
+		case errors.As(err, &exitErr):
+			hsr.Status = pluginExitStatus(exitErr.ExitCode())
+		default:
+			hsr.Status = StatusUnknown
+			hsr.Message = fmt.Sprintf("failed to run script: %v", err)
+			return hsr
+		}
+
+		if hsr.Message == "" {
+			hsr.Message = "(no output)"
+		}
+		return hsr
+	}
+}
+
+// pluginExitStatus maps a Nagios plugin exit code to a Status.
+func pluginExitStatus(code int) Status {
+	switch code {
+	case 0:
+		return StatusOK
+	case 1:
+		return StatusWarning
+	case 2:
+		return StatusCritical
+	default:
+		return StatusUnknown
+	}
+}
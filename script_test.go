@@ -0,0 +1,122 @@
+package heartbeat_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/twistingmercury/heartbeat"
+)
+
+func shScript(script string) heartbeat.ScriptOptions {
+	return heartbeat.ScriptOptions{Command: "sh", Args: []string{"-c", script}}
+}
+
+func TestScriptCheckExitCodes(t *testing.T) {
+	tests := []struct {
+		name            string
+		script          string
+		expectedStatus  heartbeat.Status
+		expectedMessage string
+	}{
+		{
+			name:            "exit 0 is OK",
+			script:          "echo 'DISK OK - free space: 72%'; exit 0",
+			expectedStatus:  heartbeat.StatusOK,
+			expectedMessage: "DISK OK - free space: 72%",
+		},
+		{
+			name:            "exit 1 is Warning",
+			script:          "echo 'DISK WARNING'; exit 1",
+			expectedStatus:  heartbeat.StatusWarning,
+			expectedMessage: "DISK WARNING",
+		},
+		{
+			name:            "exit 2 is Critical",
+			script:          "echo 'DISK CRITICAL'; exit 2",
+			expectedStatus:  heartbeat.StatusCritical,
+			expectedMessage: "DISK CRITICAL",
+		},
+		{
+			name:            "exit 3 is Unknown",
+			script:          "echo 'DISK UNKNOWN'; exit 3",
+			expectedStatus:  heartbeat.StatusUnknown,
+			expectedMessage: "DISK UNKNOWN",
+		},
+		{
+			name:            "out of range exit code is Unknown",
+			script:          "exit 42",
+			expectedStatus:  heartbeat.StatusUnknown,
+			expectedMessage: "(no output)",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := heartbeat.ScriptCheck(shScript(tt.script))()
+			assert.Equal(t, tt.expectedStatus, result.Status)
+			assert.Equal(t, tt.expectedMessage, result.Message)
+		})
+	}
+}
+
+func TestScriptCheckPerfData(t *testing.T) {
+	script := `printf 'LOAD OK - load average: 0.12 | load1=0.12;5;10;0 load5=0.20;4;6;0\nlong text\n| procs=42\n'`
+
+	result := heartbeat.ScriptCheck(shScript(script))()
+	assert.Equal(t, heartbeat.StatusOK, result.Status)
+	assert.Equal(t, "LOAD OK - load average: 0.12", result.Message)
+	if assert.Len(t, result.Metrics, 3) {
+		assert.Equal(t, "load1", result.Metrics[0].Label)
+		assert.Equal(t, 0.12, result.Metrics[0].Value)
+		assert.Equal(t, "5", result.Metrics[0].Warn)
+		assert.Equal(t, "10", result.Metrics[0].Crit)
+		assert.Equal(t, "procs", result.Metrics[2].Label)
+		assert.Equal(t, 42.0, result.Metrics[2].Value)
+	}
+}
+
+func TestScriptCheckEnvironment(t *testing.T) {
+	opts := shScript(`echo "$CHECK_TARGET"`)
+	opts.Env = []string{"CHECK_TARGET=db01"}
+
+	result := heartbeat.ScriptCheck(opts)()
+	assert.Equal(t, heartbeat.StatusOK, result.Status)
+	assert.Equal(t, "db01", result.Message)
+}
+
+func TestScriptCheckTimeout(t *testing.T) {
+	opts := shScript("sleep 5")
+	opts.Timeout = 100 * time.Millisecond
+
+	st := time.Now()
+	result := heartbeat.ScriptCheck(opts)()
+	assert.Less(t, time.Since(st), 3*time.Second)
+	assert.Equal(t, heartbeat.StatusCritical, result.Status)
+	assert.Contains(t, result.Message, "timed out")
+}
+
+func TestScriptCheckTimeoutWithinDependencyTimeout(t *testing.T) {
+	assert.Less(t, heartbeat.DefaultScriptTimeout+time.Second, heartbeat.DefaultTimeout)
+
+	opts := shScript("sleep 5")
+	opts.Timeout = 200 * time.Millisecond
+	d := heartbeat.DependencyDescriptor{
+		Name:        "script",
+		Type:        "nagios",
+		HandlerFunc: heartbeat.ScriptCheck(opts),
+		Timeout:     2 * time.Second,
+	}
+
+	result := heartbeat.CheckDependency(context.Background(), d)
+	assert.Equal(t, heartbeat.StatusCritical, result.Status)
+	assert.Equal(t, "script timed out after 200ms", result.Message)
+}
+
+func TestScriptCheckMissingCommand(t *testing.T) {
+	result := heartbeat.ScriptCheck(heartbeat.ScriptOptions{Command: "/nonexistent/check_nothing"})()
+	assert.Equal(t, heartbeat.StatusUnknown, result.Status)
+	assert.Contains(t, result.Message, "failed to run script")
+	assert.Equal(t, "/nonexistent/check_nothing", result.Resource)
+}
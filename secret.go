@@ -0,0 +1,171 @@
+package heartbeat
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Secret is a reference to a credential, resolved each time a check needs it
+// so that the credential never appears in a DependencyDescriptor.
+type Secret interface {
+	Resolve(ctx context.Context) (string, error)
+}
+
+// SecretFunc adapts a function to the Secret interface.
+type SecretFunc func(ctx context.Context) (string, error)
+
+// Resolve implements Secret.
+func (f SecretFunc) Resolve(ctx context.Context) (string, error) {
+	return f(ctx)
+}
+
+// EnvSecret is a Secret read from the named environment variable.
+func EnvSecret(name string) Secret {
+	return SecretFunc(func(context.Context) (string, error) {
+		v, ok := os.LookupEnv(name)
+		if !ok {
+			return "", fmt.Errorf("environment variable %s not set", name)
+		}
+		return v, nil
+	})
+}
+
+// FileSecret is a Secret read from a file, such as a mounted Kubernetes
+// secret, with trailing line breaks removed. The file is read again whenever
+// its modification time or size changes, so rotated secrets are picked up
+// without a restart.
+func FileSecret(path string) Secret {
+	return &fileSecret{path: path}
+}
+
+type fileSecret struct {
+	path string
+
+	mu      sync.Mutex
+	value   string
+	modTime time.Time
+	size    int64
+	loaded  bool
+}
+
+func (s *fileSecret) Resolve(context.Context) (string, error) {
+	info, err := os.Stat(s.path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read secret file: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.loaded && info.ModTime().Equal(s.modTime) && info.Size() == s.size {
+		return s.value, nil
+	}
+	b, err := os.ReadFile(s.path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read secret file: %w", err)
+	}
+	s.value = strings.TrimRight(string(b), "\r\n")
+	s.modTime = info.ModTime()
+	s.size = info.Size()
+	s.loaded = true
+	return s.value, nil
+}
+
+// SecretProvider looks up secrets by key in a secret manager, such as Vault or
+// a cloud provider's secret store.
+type SecretProvider interface {
+	LookupSecret(ctx context.Context, key string) (string, error)
+}
+
+// ProviderSecret is a Secret looked up by key from the provider.
+func ProviderSecret(p SecretProvider, key string) Secret {
+	return SecretFunc(func(ctx context.Context) (string, error) {
+		return p.LookupSecret(ctx, key)
+	})
+}
+
+// secretPlaceholder matches the ${name} references to DependencyDescriptor.Secrets.
+var secretPlaceholder = regexp.MustCompile(`\$\{([A-Za-z0-9_.-]+)\}`)
+
+// resolveConnection returns the Connection with its ${name} placeholders
+// replaced by the URL-escaped values of the descriptor's Secrets, and the
+// values themselves.
+func (d *DependencyDescriptor) resolveConnection(ctx context.Context) (string, []string, error) {
+	if len(d.Secrets) == 0 {
+		return d.Connection, nil, nil
+	}
+	var (
+		values []string
+		errs   []error
+	)
+	conn := secretPlaceholder.ReplaceAllStringFunc(d.Connection, func(m string) string {
+		name := secretPlaceholder.FindStringSubmatch(m)[1]
+		secret, ok := d.Secrets[name]
+		if !ok || secret == nil {
+			errs = append(errs, fmt.Errorf("unknown secret %q", name))
+			return m
+		}
+		v, err := secret.Resolve(ctx)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to resolve secret %q: %w", name, err))
+			return m
+		}
+		values = append(values, v)
+		return escapeSecret(v)
+	})
+	return conn, values, errors.Join(errs...)
+}
+
+// escapeSecret percent-encodes all but the unreserved characters of a URL, so
+// that a secret can be placed in any part of it.
+func escapeSecret(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' || c == '-' || c == '.' || c == '_' || c == '~' {
+			b.WriteByte(c)
+			continue
+		}
+		fmt.Fprintf(&b, "%%%02X", c)
+	}
+	return b.String()
+}
+
+// probeSecretURL checks the descriptor's Connection with its secrets
+// resolved, reporting the Connection, not the resolved URL, as the resource
+// and keeping the secret values out of the message.
+func probeSecretURL(ctx context.Context, d DependencyDescriptor, timeout time.Duration) (StatusResult, RetryOn) {
+	// Bound resolving the secrets and the request together by the timeout
+	reqCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	target, values, err := d.resolveConnection(reqCtx)
+	if err != nil {
+		return StatusResult{
+			Status:   StatusCritical,
+			Name:     d.Connection,
+			Resource: d.Connection,
+			Message:  err.Error(),
+		}, 0
+	}
+
+	hsr, failure := probeURLWithin(ctx, reqCtx, target, d.Auth)
+	if target == d.Connection {
+		return hsr, failure
+	}
+	hsr.Name = d.Connection
+	hsr.Resource = d.Connection
+	hsr.Message = strings.ReplaceAll(hsr.Message, target, d.Connection)
+	for _, v := range values {
+		if v == "" {
+			continue
+		}
+		hsr.Message = strings.ReplaceAll(hsr.Message, escapeSecret(v), Redacted)
+		hsr.Message = strings.ReplaceAll(hsr.Message, v, Redacted)
+	}
+	return hsr, failure
+}
@@ -0,0 +1,104 @@
+package heartbeat
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// StatusHeader is the conventional header for the overall Status, set with
+// StatusHeaders.
+const StatusHeader = "X-Health-Status"
+
+// StatusCodes maps the overall Status of a Response to its HTTP status code.
+type StatusCodes map[Status]int
+
+// DefaultStatusCodes returns the default mapping: 200 OK for NotSet, OK and
+// Warning, and 503 Service Unavailable for Unknown and Critical.
+func DefaultStatusCodes() StatusCodes {
+	return StatusCodes{
+		StatusNotSet:   http.StatusOK,
+		StatusOK:       http.StatusOK,
+		StatusWarning:  http.StatusOK, // still operational but degraded
+		StatusUnknown:  http.StatusServiceUnavailable,
+		StatusCritical: http.StatusServiceUnavailable,
+	}
+}
+
+// code returns the HTTP status code for the status.
+func (sc StatusCodes) code(s Status) int {
+	if code, ok := sc[s]; ok {
+		return code
+	}
+	if s.failing() {
+		return http.StatusServiceUnavailable
+	}
+	return http.StatusOK
+}
+
+// WithStatusCodes overrides the HTTP status codes of the given statuses, such
+// as Warning to 429 Too Many Requests for a load balancer that should shed
+// load, or NotSet to 503 for a startup probe. Statuses left out keep their
+// DefaultStatusCodes. Codes outside 200-599 are ignored, as informational 1xx
+// codes don't end the response.
+func WithStatusCodes(codes StatusCodes) Option {
+	return func(cfg *config) {
+		for s, code := range codes {
+			if code >= 200 && code <= 599 {
+				cfg.statusCodes[s] = code
+			}
+		}
+	}
+}
+
+// ResponseHeaders returns headers to add to a health response, given its
+// overall Status and HTTP status code. It may return nil.
+type ResponseHeaders func(status Status, httpStatus int) http.Header
+
+// WithResponseHeaders adds the headers returned by each function to every
+// health response, after those of earlier functions.
+func WithResponseHeaders(headers ...ResponseHeaders) Option {
+	return func(cfg *config) {
+		for _, h := range headers {
+			if h != nil {
+				cfg.headers = append(cfg.headers, h)
+			}
+		}
+	}
+}
+
+// StatusHeaders sets the StatusHeader to the overall Status, such as
+// "Warning", so that load balancers can match on it without parsing the body.
+func StatusHeaders() ResponseHeaders {
+	return func(status Status, _ int) http.Header {
+		return http.Header{StatusHeader: {status.String()}}
+	}
+}
+
+// RetryAfter sets the Retry-After header, in whole seconds, on responses with
+// one of the HTTP status codes, by default 429 and 503.
+func RetryAfter(d time.Duration, httpStatuses ...int) ResponseHeaders {
+	if len(httpStatuses) == 0 {
+		httpStatuses = []int{http.StatusTooManyRequests, http.StatusServiceUnavailable}
+	}
+	seconds := strconv.Itoa(int(d.Round(time.Second) / time.Second))
+	return func(_ Status, httpStatus int) http.Header {
+		if !slices.Contains(httpStatuses, httpStatus) {
+			return nil
+		}
+		return http.Header{"Retry-After": {seconds}}
+	}
+}
+
+// writeHeaders adds the configured response headers for the status.
+func (cfg *config) writeHeaders(h http.Header, status Status, httpStatus int) {
+	for _, fn := range cfg.headers {
+		for name, values := range fn(status, httpStatus) {
+			h.Del(name)
+			for _, v := range values {
+				h.Add(name, v)
+			}
+		}
+	}
+}
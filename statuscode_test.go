@@ -0,0 +1,110 @@
+package heartbeat_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/twistingmercury/heartbeat"
+)
+
+func TestDefaultStatusCodes(t *testing.T) {
+	deps, status := switchableDeps()
+	r := healthHandler(deps)
+
+	tests := []struct {
+		status heartbeat.Status
+		code   int
+	}{
+		{heartbeat.StatusOK, http.StatusOK},
+		{heartbeat.StatusWarning, http.StatusOK},
+		{heartbeat.StatusUnknown, http.StatusServiceUnavailable},
+		{heartbeat.StatusCritical, http.StatusServiceUnavailable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.status.String(), func(t *testing.T) {
+			status.Store(int32(tt.status))
+			resp := serveHealth(r, context.Background())
+			assert.Equal(t, tt.code, resp.Code)
+			assert.Empty(t, resp.Header().Get(heartbeat.StatusHeader))
+		})
+	}
+	assert.Equal(t, http.StatusOK, serveHealth(healthHandler(nil), context.Background()).Code)
+}
+
+func TestWithStatusCodes(t *testing.T) {
+	deps, status := switchableDeps()
+	r := healthHandler(deps, heartbeat.WithStatusCodes(heartbeat.StatusCodes{
+		heartbeat.StatusWarning: http.StatusTooManyRequests,
+		heartbeat.StatusUnknown: 42, // ignored
+	}))
+
+	status.Store(int32(heartbeat.StatusWarning))
+	assert.Equal(t, http.StatusTooManyRequests, serveHealth(r, context.Background()).Code)
+	status.Store(int32(heartbeat.StatusUnknown))
+	assert.Equal(t, http.StatusServiceUnavailable, serveHealth(r, context.Background()).Code)
+	status.Store(int32(heartbeat.StatusOK))
+	assert.Equal(t, http.StatusOK, serveHealth(r, context.Background()).Code)
+
+	startup := healthHandler(nil, heartbeat.WithStatusCodes(heartbeat.StatusCodes{
+		heartbeat.StatusNotSet: http.StatusServiceUnavailable,
+	}))
+	assert.Equal(t, http.StatusServiceUnavailable, serveHealth(startup, context.Background()).Code)
+}
+
+func TestWithStatusCodesIgnoresInvalidCodes(t *testing.T) {
+	deps, status := switchableDeps()
+	r := healthHandler(deps, heartbeat.WithStatusCodes(heartbeat.StatusCodes{
+		heartbeat.StatusOK:       150, // informational, would end up as an empty 200
+		heartbeat.StatusWarning:  199,
+		heartbeat.StatusCritical: 600,
+	}))
+
+	status.Store(int32(heartbeat.StatusOK))
+	resp := serveHealth(r, context.Background())
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.NotEmpty(t, resp.Body.String())
+	status.Store(int32(heartbeat.StatusWarning))
+	assert.Equal(t, http.StatusOK, serveHealth(r, context.Background()).Code)
+	status.Store(int32(heartbeat.StatusCritical))
+	assert.Equal(t, http.StatusServiceUnavailable, serveHealth(r, context.Background()).Code)
+}
+
+func TestWithResponseHeaders(t *testing.T) {
+	deps, status := switchableDeps()
+	r := healthHandler(deps,
+		heartbeat.WithStatusCodes(heartbeat.StatusCodes{heartbeat.StatusWarning: http.StatusTooManyRequests}),
+		heartbeat.WithResponseHeaders(
+			heartbeat.StatusHeaders(),
+			heartbeat.RetryAfter(30*time.Second),
+			func(s heartbeat.Status, httpStatus int) http.Header {
+				return http.Header{"Cache-Control": {"no-store"}}
+			},
+		),
+	)
+
+	status.Store(int32(heartbeat.StatusOK))
+	resp := serveHealth(r, context.Background())
+	assert.Equal(t, "OK", resp.Header().Get(heartbeat.StatusHeader))
+	assert.Empty(t, resp.Header().Get("Retry-After"))
+	assert.Equal(t, "no-store", resp.Header().Get("Cache-Control"))
+
+	status.Store(int32(heartbeat.StatusWarning))
+	resp = serveHealth(r, context.Background())
+	assert.Equal(t, http.StatusTooManyRequests, resp.Code)
+	assert.Equal(t, "Warning", resp.Header().Get(heartbeat.StatusHeader))
+	assert.Equal(t, "30", resp.Header().Get("Retry-After"))
+
+	status.Store(int32(heartbeat.StatusCritical))
+	resp = serveHealth(r, context.Background())
+	assert.Equal(t, "Critical", resp.Header().Get(heartbeat.StatusHeader))
+	assert.Equal(t, "30", resp.Header().Get("Retry-After"))
+}
+
+func TestRetryAfterStatuses(t *testing.T) {
+	headers := heartbeat.RetryAfter(1500*time.Millisecond, http.StatusServiceUnavailable)
+	assert.Equal(t, http.Header{"Retry-After": {"2"}}, headers(heartbeat.StatusCritical, http.StatusServiceUnavailable))
+	assert.Nil(t, headers(heartbeat.StatusWarning, http.StatusTooManyRequests))
+}
@@ -0,0 +1,389 @@
+package heartbeat
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// HistoryStore persists check runs so that History and Uptime survive restarts.
+type HistoryStore interface {
+	// Append stores a check run.
+	Append(run CheckRun) error
+	// Load calls fn with the stored runs that completed at or after since,
+	// oldest first, until fn returns an error.
+	Load(since time.Time, fn func(CheckRun) error) error
+}
+
+// WithHistoryStore appends every check run of the handler to the store.
+// Append errors are passed to onError, when set; they don't fail the request.
+func WithHistoryStore(store HistoryStore, onError func(error)) Option {
+	return func(cfg *config) {
+		if store == nil {
+			return
+		}
+		cfg.observers = append(cfg.observers, ObserverFunc(func(run CheckRun) {
+			if err := store.Append(run); err != nil && onError != nil {
+				onError(err)
+			}
+		}))
+	}
+}
+
+// RestoreHistory replays the runs stored since the given time to the
+// observers, such as a History and an Uptime, before the handler starts
+// serving:
+//
+//	store, err := heartbeat.OpenFileStore(heartbeat.FileStoreOptions{Dir: "/var/lib/heartbeat"})
+//	...
+//	err = heartbeat.RestoreHistory(store, time.Now().Add(-30*24*time.Hour), history, uptime)
+func RestoreHistory(store HistoryStore, since time.Time, observers ...Observer) error {
+	return store.Load(since, func(run CheckRun) error {
+		for _, o := range observers {
+			o.ObserveRun(run)
+		}
+		return nil
+	})
+}
+
+// runRecord is the stored form of a CheckRun.
+type runRecord struct {
+	Time     time.Time      `json:"time"`
+	Service  string         `json:"service"`
+	Status   Status         `json:"status"`
+	Duration float64        `json:"duration_ms"`
+	Results  []resultRecord `json:"results,omitempty"`
+}
+
+type resultRecord struct {
+	Name       string  `json:"name"`
+	Type       string  `json:"type,omitempty"`
+	Status     Status  `json:"status"`
+	Duration   float64 `json:"duration_ms"`
+	StatusCode int     `json:"http_status_code,omitempty"`
+	Message    string  `json:"message,omitempty"`
+}
+
+func newRunRecord(run CheckRun) runRecord {
+	rec := runRecord{
+		Time:     run.Time.UTC(),
+		Service:  run.Service,
+		Status:   run.Status,
+		Duration: float64(run.Duration.Microseconds()) / 1000,
+		Results:  make([]resultRecord, len(run.Results)),
+	}
+	for i, r := range run.Results {
+		rec.Results[i] = resultRecord{
+			Name:       r.Name,
+			Type:       r.Type,
+			Status:     r.Status,
+			Duration:   r.RequestDuration,
+			StatusCode: r.StatusCode,
+			Message:    r.Message,
+		}
+	}
+	return rec
+}
+
+func (rec runRecord) run() CheckRun {
+	run := CheckRun{
+		Service:  rec.Service,
+		Status:   rec.Status,
+		Time:     rec.Time,
+		Duration: time.Duration(rec.Duration * float64(time.Millisecond)),
+		Results:  make([]StatusResult, len(rec.Results)),
+	}
+	for i, r := range rec.Results {
+		run.Results[i] = StatusResult{
+			Status:          r.Status,
+			Name:            r.Name,
+			Type:            r.Type,
+			Resource:        r.Name,
+			RequestDuration: r.Duration,
+			StatusCode:      r.StatusCode,
+			Message:         r.Message,
+		}
+	}
+	return run
+}
+
+const (
+	defaultSegmentSize = 8 << 20
+	segmentExt         = ".jsonl"
+)
+
+// FileStoreOptions configures a FileStore.
+type FileStoreOptions struct {
+	// Dir holds the segment files. It is created if it doesn't exist.
+	Dir string
+	// SegmentSize is the size in bytes at which a new segment file is started.
+	// Defaults to 8 MiB.
+	SegmentSize int64
+	// Retention is how long runs are kept. Segments holding only older runs
+	// are deleted, and older runs are not loaded. Zero keeps runs forever.
+	Retention time.Duration
+	// MaxBytes caps the total size of the segments; the oldest are deleted to
+	// stay under it. The segment being written is never deleted. Zero doesn't
+	// limit the size.
+	MaxBytes int64
+}
+
+// FileStore is a HistoryStore that appends runs as JSON lines to segment
+// files in a directory. When a segment reaches its size limit, a new one is
+// started and the store is compacted by deleting segments past the retention
+// and size limits. A line left incomplete by a crash is skipped on load.
+type FileStore struct {
+	opts FileStoreOptions
+
+	mu       sync.Mutex
+	segments []segment
+	active   *os.File
+	now      func() time.Time
+}
+
+// segment is a file named after its sequence number and the time of its
+// first run, so that its runs are known to be older than the next segment's.
+type segment struct {
+	path  string
+	seq   uint64
+	first time.Time
+	size  int64
+}
+
+// OpenFileStore opens the store in opts.Dir, picking up the segments written
+// by earlier processes and deleting those past the retention and size limits,
+// which may have been tightened since.
+func OpenFileStore(opts FileStoreOptions) (*FileStore, error) {
+	if opts.Dir == "" {
+		return nil, errors.New("file store directory not set")
+	}
+	if opts.SegmentSize <= 0 {
+		opts.SegmentSize = defaultSegmentSize
+	}
+	if err := os.MkdirAll(opts.Dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create file store directory: %w", err)
+	}
+
+	entries, err := os.ReadDir(opts.Dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file store directory: %w", err)
+	}
+	fs := &FileStore{opts: opts, now: time.Now}
+	for _, e := range entries {
+		seg, ok := parseSegmentName(e.Name())
+		if !ok || e.IsDir() {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read file store segment: %w", err)
+		}
+		seg.path = filepath.Join(opts.Dir, e.Name())
+		seg.size = info.Size()
+		fs.segments = append(fs.segments, seg)
+	}
+	sort.Slice(fs.segments, func(i, j int) bool { return fs.segments[i].seq < fs.segments[j].seq })
+	if err := fs.compact(); err != nil {
+		return nil, err
+	}
+	return fs, nil
+}
+
+func segmentName(seq uint64, first time.Time) string {
+	return fmt.Sprintf("%020d-%d%s", seq, first.UnixNano(), segmentExt)
+}
+
+func parseSegmentName(name string) (segment, bool) {
+	seqPart, firstPart, ok := strings.Cut(strings.TrimSuffix(name, segmentExt), "-")
+	if !ok || !strings.HasSuffix(name, segmentExt) {
+		return segment{}, false
+	}
+	seq, err := strconv.ParseUint(seqPart, 10, 64)
+	if err != nil {
+		return segment{}, false
+	}
+	first, err := strconv.ParseInt(firstPart, 10, 64)
+	if err != nil {
+		return segment{}, false
+	}
+	return segment{seq: seq, first: time.Unix(0, first)}, true
+}
+
+// Append implements HistoryStore.
+func (fs *FileStore) Append(run CheckRun) error {
+	line, err := json.Marshal(newRunRecord(run))
+	if err != nil {
+		return fmt.Errorf("failed to encode check run: %w", err)
+	}
+	line = append(line, '\n')
+
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	last := len(fs.segments) - 1
+	rotated := last < 0 || (fs.segments[last].size > 0 && fs.segments[last].size+int64(len(line)) > fs.opts.SegmentSize)
+	if rotated {
+		if err := fs.rotate(run.Time); err != nil {
+			return err
+		}
+		last = len(fs.segments) - 1
+	}
+	if fs.active == nil {
+		f, err := os.OpenFile(fs.segments[last].path, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0o644)
+		if err != nil {
+			return fmt.Errorf("failed to open file store segment: %w", err)
+		}
+		fs.active = f
+
+		// Terminate a line left incomplete by a crash, so that it doesn't
+		// swallow the next run.
+		if size := fs.segments[last].size; size > 0 {
+			b := make([]byte, 1)
+			if _, err := f.ReadAt(b, size-1); err == nil && b[0] != '\n' {
+				line = append([]byte{'\n'}, line...)
+			}
+		}
+	}
+
+	n, err := fs.active.Write(line)
+	fs.segments[last].size += int64(n)
+	if err != nil {
+		return fmt.Errorf("failed to append check run: %w", err)
+	}
+	if rotated {
+		return fs.compact()
+	}
+	return nil
+}
+
+// rotate starts a new segment. The caller holds fs.mu.
+func (fs *FileStore) rotate(first time.Time) error {
+	if fs.active != nil {
+		if err := fs.active.Close(); err != nil {
+			return fmt.Errorf("failed to close file store segment: %w", err)
+		}
+		fs.active = nil
+	}
+
+	var seq uint64
+	if n := len(fs.segments); n > 0 {
+		seq = fs.segments[n-1].seq + 1
+	}
+	fs.segments = append(fs.segments, segment{
+		path:  filepath.Join(fs.opts.Dir, segmentName(seq, first)),
+		seq:   seq,
+		first: first,
+	})
+	return nil
+}
+
+// Compact deletes the segments past the retention and size limits.
+func (fs *FileStore) Compact() error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	return fs.compact()
+}
+
+func (fs *FileStore) compact() error {
+	var total int64
+	for _, seg := range fs.segments {
+		total += seg.size
+	}
+
+	cutoff := fs.cutoff()
+	drop := 0
+	for drop < len(fs.segments)-1 {
+		expired := !cutoff.IsZero() && fs.segments[drop+1].first.Before(cutoff)
+		oversized := fs.opts.MaxBytes > 0 && total > fs.opts.MaxBytes
+		if !expired && !oversized {
+			break
+		}
+		if err := os.Remove(fs.segments[drop].path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			fs.segments = fs.segments[drop:]
+			return fmt.Errorf("failed to delete file store segment: %w", err)
+		}
+		total -= fs.segments[drop].size
+		drop++
+	}
+	fs.segments = fs.segments[drop:]
+	return nil
+}
+
+// cutoff returns the time before which runs are past retention, or zero.
+func (fs *FileStore) cutoff() time.Time {
+	if fs.opts.Retention <= 0 {
+		return time.Time{}
+	}
+	return fs.now().Add(-fs.opts.Retention)
+}
+
+// Load implements HistoryStore.
+func (fs *FileStore) Load(since time.Time, fn func(CheckRun) error) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	if cutoff := fs.cutoff(); cutoff.After(since) {
+		since = cutoff
+	}
+	for i, seg := range fs.segments {
+		if i+1 < len(fs.segments) && fs.segments[i+1].first.Before(since) {
+			continue
+		}
+		if err := loadSegment(seg.path, since, fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func loadSegment(path string, since time.Time, fn func(CheckRun) error) error {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to open file store segment: %w", err)
+	}
+	defer func() {
+		_ = f.Close() // Error intentionally ignored - read-only file
+	}()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64<<10), 16<<20)
+	for scanner.Scan() {
+		var rec runRecord
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			continue // incomplete or corrupt line
+		}
+		if rec.Time.Before(since) {
+			continue
+		}
+		if err := fn(rec.run()); err != nil {
+			return err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read file store segment: %w", err)
+	}
+	return nil
+}
+
+// Close closes the segment being written.
+func (fs *FileStore) Close() error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	if fs.active == nil {
+		return nil
+	}
+	err := fs.active.Close()
+	fs.active = nil
+	return err
+}
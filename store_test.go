@@ -0,0 +1,203 @@
+package heartbeat_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/twistingmercury/heartbeat"
+)
+
+var storeStart = time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func storedRun(at time.Duration, status heartbeat.Status) heartbeat.CheckRun {
+	return heartbeat.CheckRun{
+		Service:  "unit-test",
+		Status:   status,
+		Time:     storeStart.Add(at),
+		Duration: 3 * time.Millisecond,
+		Results: []heartbeat.StatusResult{
+			{Name: "cache", Type: "redis", Status: status, RequestDuration: 2.5, Message: status.String()},
+		},
+	}
+}
+
+func openFileStore(t *testing.T, opts heartbeat.FileStoreOptions, now time.Time) *heartbeat.FileStore {
+	t.Helper()
+	store, err := heartbeat.OpenFileStore(opts)
+	require.NoError(t, err)
+	store.SetClock(func() time.Time { return now })
+	t.Cleanup(func() { store.Close() })
+	return store
+}
+
+func loadRuns(t *testing.T, store heartbeat.HistoryStore, since time.Time) []heartbeat.CheckRun {
+	t.Helper()
+	var runs []heartbeat.CheckRun
+	require.NoError(t, store.Load(since, func(run heartbeat.CheckRun) error {
+		runs = append(runs, run)
+		return nil
+	}))
+	return runs
+}
+
+func segmentFiles(t *testing.T, dir string) []string {
+	t.Helper()
+	files, err := filepath.Glob(filepath.Join(dir, "*.jsonl"))
+	require.NoError(t, err)
+	return files
+}
+
+func TestFileStoreReloadsAcrossRestarts(t *testing.T) {
+	dir := t.TempDir()
+	store := openFileStore(t, heartbeat.FileStoreOptions{Dir: dir}, storeStart)
+	require.NoError(t, store.Append(storedRun(0, heartbeat.StatusOK)))
+	require.NoError(t, store.Append(storedRun(time.Minute, heartbeat.StatusCritical)))
+	require.NoError(t, store.Close())
+
+	store = openFileStore(t, heartbeat.FileStoreOptions{Dir: dir}, storeStart)
+	require.NoError(t, store.Append(storedRun(2*time.Minute, heartbeat.StatusOK)))
+
+	history := heartbeat.NewHistory(10)
+	uptime := heartbeat.NewUptime(heartbeat.UptimeOptions{})
+	uptime.SetClock(func() time.Time { return storeStart.Add(3 * time.Minute) })
+	require.NoError(t, heartbeat.RestoreHistory(store, time.Time{}, history, uptime))
+
+	entries := history.Dependency("cache", time.Time{}, time.Time{})
+	require.Len(t, entries, 3)
+	assert.Equal(t, storeStart.Add(time.Minute), entries[1].Time)
+	assert.Equal(t, heartbeat.StatusCritical, entries[1].Status)
+	assert.Equal(t, 2.5, entries[1].Duration)
+	assert.Equal(t, "Critical", entries[1].Message)
+
+	report := uptime.Report("unit-test")
+	require.Len(t, report.Dependencies, 1)
+	assert.Equal(t, 3, report.Dependencies[0].Availability[0].Checks)
+	assert.Equal(t, 3, report.Service.Availability[0].Checks)
+
+	runs := loadRuns(t, store, storeStart.Add(time.Minute))
+	require.Len(t, runs, 2)
+	assert.Equal(t, "unit-test", runs[0].Service)
+	assert.Equal(t, 3*time.Millisecond, runs[0].Duration)
+}
+
+func TestFileStoreRetention(t *testing.T) {
+	dir := t.TempDir()
+	now := storeStart.Add(10 * time.Hour)
+	store := openFileStore(t, heartbeat.FileStoreOptions{Dir: dir, SegmentSize: 1, Retention: 5 * time.Hour}, now)
+
+	// A tiny segment size puts every run in a segment of its own.
+	for i := range 10 {
+		require.NoError(t, store.Append(storedRun(time.Duration(i)*time.Hour, heartbeat.StatusOK)))
+	}
+	// Segments 0 to 3 are deleted. Segment 4 is kept, as it may hold runs up
+	// to the start of segment 5 at the cutoff, but its run isn't loaded.
+	assert.Len(t, segmentFiles(t, dir), 6)
+
+	runs := loadRuns(t, store, time.Time{})
+	require.Len(t, runs, 5)
+	assert.Equal(t, storeStart.Add(5*time.Hour), runs[0].Time)
+}
+
+func TestFileStoreMaxBytes(t *testing.T) {
+	dir := t.TempDir()
+	store := openFileStore(t, heartbeat.FileStoreOptions{Dir: dir, SegmentSize: 1, MaxBytes: 1}, storeStart)
+
+	for i := range 5 {
+		require.NoError(t, store.Append(storedRun(time.Duration(i)*time.Minute, heartbeat.StatusOK)))
+	}
+	// Only the segment being written survives.
+	assert.Len(t, segmentFiles(t, dir), 1)
+	runs := loadRuns(t, store, time.Time{})
+	require.Len(t, runs, 1)
+	assert.Equal(t, storeStart.Add(4*time.Minute), runs[0].Time)
+
+	require.NoError(t, store.Compact())
+	assert.Len(t, segmentFiles(t, dir), 1)
+}
+
+func TestOpenFileStoreCompacts(t *testing.T) {
+	dir := t.TempDir()
+	store := openFileStore(t, heartbeat.FileStoreOptions{Dir: dir, SegmentSize: 1}, storeStart)
+	for i := range 5 {
+		require.NoError(t, store.Append(storedRun(time.Duration(i)*time.Minute, heartbeat.StatusOK)))
+	}
+	require.NoError(t, store.Close())
+	assert.Len(t, segmentFiles(t, dir), 5)
+
+	// Reopened with a size limit, the store drops all but the last segment
+	// before anything is appended.
+	store = openFileStore(t, heartbeat.FileStoreOptions{Dir: dir, SegmentSize: 1, MaxBytes: 1}, storeStart)
+	assert.Len(t, segmentFiles(t, dir), 1)
+	runs := loadRuns(t, store, time.Time{})
+	require.Len(t, runs, 1)
+	assert.Equal(t, storeStart.Add(4*time.Minute), runs[0].Time)
+}
+
+func TestFileStoreSkipsIncompleteLines(t *testing.T) {
+	dir := t.TempDir()
+	store := openFileStore(t, heartbeat.FileStoreOptions{Dir: dir}, storeStart)
+	require.NoError(t, store.Append(storedRun(0, heartbeat.StatusOK)))
+	require.NoError(t, store.Close())
+
+	// Simulate a crash in the middle of a write.
+	files := segmentFiles(t, dir)
+	require.Len(t, files, 1)
+	f, err := os.OpenFile(files[0], os.O_WRONLY|os.O_APPEND, 0)
+	require.NoError(t, err)
+	_, err = f.WriteString(`{"time":"2025-01-01T00:01:00Z","serv`)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	store = openFileStore(t, heartbeat.FileStoreOptions{Dir: dir}, storeStart)
+	require.NoError(t, store.Append(storedRun(2*time.Minute, heartbeat.StatusCritical)))
+
+	runs := loadRuns(t, store, time.Time{})
+	require.Len(t, runs, 2)
+	assert.Equal(t, heartbeat.StatusCritical, runs[1].Status)
+}
+
+func TestFileStoreLoadStopsOnError(t *testing.T) {
+	store := openFileStore(t, heartbeat.FileStoreOptions{Dir: t.TempDir()}, storeStart)
+	require.NoError(t, store.Append(storedRun(0, heartbeat.StatusOK)))
+	require.NoError(t, store.Append(storedRun(time.Minute, heartbeat.StatusOK)))
+
+	stop := errors.New("stop")
+	calls := 0
+	err := store.Load(time.Time{}, func(heartbeat.CheckRun) error {
+		calls++
+		return stop
+	})
+	assert.ErrorIs(t, err, stop)
+	assert.Equal(t, 1, calls)
+}
+
+func TestOpenFileStoreRequiresDir(t *testing.T) {
+	_, err := heartbeat.OpenFileStore(heartbeat.FileStoreOptions{})
+	assert.Error(t, err)
+}
+
+func TestWithHistoryStoreAppendsRuns(t *testing.T) {
+	deps, _ := switchableDeps()
+	store := openFileStore(t, heartbeat.FileStoreOptions{Dir: t.TempDir()}, time.Now())
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/health", heartbeat.NewHandler("unit-test", deps, heartbeat.WithHistoryStore(store, func(err error) {
+		assert.NoError(t, err)
+	})))
+	healthResponse(t, r)
+	healthResponse(t, r)
+
+	runs := loadRuns(t, store, time.Time{})
+	require.Len(t, runs, 2)
+	assert.Equal(t, heartbeat.StatusOK, runs[0].Status)
+	require.Len(t, runs[0].Results, 2)
+	assert.Equal(t, "db", runs[0].Results[0].Name)
+	assert.Equal(t, "cache", runs[0].Results[1].Name)
+}
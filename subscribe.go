@@ -0,0 +1,152 @@
+package heartbeat
+
+import (
+	"sync"
+)
+
+// StatusFeed delivers status transitions to subscribers, so an application can
+// react to the health of its dependencies without calling the health endpoint.
+// The feed only learns of the check runs it observes, so register it with a
+// Monitor, which runs the checks on an interval:
+//
+//	feed := heartbeat.NewStatusFeed()
+//	m := heartbeat.NewMonitor("orders", deps, heartbeat.WithObserver(feed))
+//	go m.Run(ctx, 30*time.Second)
+//	r.GET("/health", m.Handler())
+//
+//	feed.Subscribe(func(t heartbeat.Transition) {
+//		if t.Dependency == "redis" {
+//			cache.UseFallback(t.Status.Severity() >= heartbeat.StatusUnknown.Severity())
+//		}
+//	})
+//
+// A first status counts as a transition only when it is Warning or worse, and a
+// repeated status never does. Each subscriber receives every transition that
+// occurs while it is subscribed, exactly once and in the order the transitions
+// occurred: within a check run, dependency transitions come in the order of
+// the handler's dependencies, followed by the aggregate one.
+// Delivery is asynchronous, on a goroutine per subscriber, so a slow
+// subscriber delays neither the health checks nor other subscribers; its
+// pending transitions are queued without bound until it catches up.
+type StatusFeed struct {
+	tracker transitionTracker
+
+	mu   sync.Mutex
+	subs map[*subscriber]struct{}
+}
+
+// NewStatusFeed returns a feed without subscribers.
+func NewStatusFeed() *StatusFeed {
+	return &StatusFeed{subs: make(map[*subscriber]struct{})}
+}
+
+// ObserveRun implements Observer.
+func (f *StatusFeed) ObserveRun(run CheckRun) {
+	// Hold the lock while tracking so that concurrent runs are queued in the
+	// order their transitions were detected.
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, t := range f.tracker.track(run) {
+		for s := range f.subs {
+			s.push(t)
+		}
+	}
+}
+
+// Subscribe calls fn with every subsequent transition until the returned
+// function is called. Calls to fn are sequential. Once unsubscribed, fn is not
+// called again, apart from a call that is already in progress.
+func (f *StatusFeed) Subscribe(fn func(Transition)) (unsubscribe func()) {
+	s := newSubscriber(fn)
+	go s.run()
+	return f.add(s)
+}
+
+// SubscribeChan returns a channel that receives every subsequent transition
+// until the returned function is called, which closes the channel. Transitions
+// not yet received when unsubscribing are discarded.
+func (f *StatusFeed) SubscribeChan() (<-chan Transition, func()) {
+	ch := make(chan Transition)
+	var s *subscriber
+	s = newSubscriber(func(t Transition) {
+		select {
+		case ch <- t:
+		case <-s.done:
+		}
+	})
+	go func() {
+		s.run()
+		close(ch)
+	}()
+	return ch, f.add(s)
+}
+
+func (f *StatusFeed) add(s *subscriber) func() {
+	f.mu.Lock()
+	f.subs[s] = struct{}{}
+	f.mu.Unlock()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			f.mu.Lock()
+			delete(f.subs, s)
+			f.mu.Unlock()
+			close(s.done)
+		})
+	}
+}
+
+// subscriber queues transitions for delivery by its own goroutine.
+type subscriber struct {
+	deliver func(Transition)
+
+	mu    sync.Mutex
+	queue []Transition
+	wake  chan struct{}
+	done  chan struct{}
+}
+
+func newSubscriber(deliver func(Transition)) *subscriber {
+	return &subscriber{
+		deliver: deliver,
+		wake:    make(chan struct{}, 1),
+		done:    make(chan struct{}),
+	}
+}
+
+func (s *subscriber) push(t Transition) {
+	s.mu.Lock()
+	s.queue = append(s.queue, t)
+	s.mu.Unlock()
+
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// run delivers queued transitions until the subscriber is unsubscribed.
+func (s *subscriber) run() {
+	for {
+		s.mu.Lock()
+		queue := s.queue
+		s.queue = nil
+		s.mu.Unlock()
+
+		for _, t := range queue {
+			select {
+			case <-s.done:
+				return
+			default:
+			}
+			s.deliver(t)
+		}
+
+		select {
+		case <-s.wake:
+		case <-s.done:
+			return
+		}
+	}
+}
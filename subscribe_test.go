@@ -0,0 +1,143 @@
+package heartbeat_test
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/twistingmercury/heartbeat"
+)
+
+// switchableDeps returns a cache dependency whose status is set by the caller,
+// and a db dependency that is always OK.
+func switchableDeps() ([]heartbeat.DependencyDescriptor, *atomic.Int32) {
+	var status atomic.Int32
+	status.Store(int32(heartbeat.StatusOK))
+	return []heartbeat.DependencyDescriptor{
+		{Name: "db", Type: "database", HandlerFunc: func() heartbeat.StatusResult {
+			return heartbeat.StatusResult{Status: heartbeat.StatusOK}
+		}},
+		{Name: "cache", Type: "redis", HandlerFunc: func() heartbeat.StatusResult {
+			s := heartbeat.Status(status.Load())
+			return heartbeat.StatusResult{Status: s, Message: "cache is " + s.String()}
+		}},
+	}, &status
+}
+
+func receive(t *testing.T, ch <-chan heartbeat.Transition) heartbeat.Transition {
+	t.Helper()
+	select {
+	case tr := <-ch:
+		return tr
+	case <-time.After(time.Second):
+		require.FailNow(t, "no transition received")
+		return heartbeat.Transition{}
+	}
+}
+
+func TestStatusFeedDeliversTransitionsInOrder(t *testing.T) {
+	deps, status := switchableDeps()
+	feed := heartbeat.NewStatusFeed()
+	ch, unsubscribe := feed.SubscribeChan()
+	defer unsubscribe()
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/health", heartbeat.NewHandler("unit-test", deps, heartbeat.WithObserver(feed)))
+
+	healthResponse(t, r)
+	status.Store(int32(heartbeat.StatusCritical))
+	healthResponse(t, r)
+	healthResponse(t, r)
+	status.Store(int32(heartbeat.StatusOK))
+	healthResponse(t, r)
+
+	down := receive(t, ch)
+	assert.False(t, down.Aggregate)
+	assert.Equal(t, "unit-test", down.Service)
+	assert.Equal(t, "cache", down.Dependency)
+	assert.Equal(t, "redis", down.Type)
+	assert.Equal(t, heartbeat.StatusOK, down.Previous)
+	assert.Equal(t, heartbeat.StatusCritical, down.Status)
+	assert.Equal(t, "cache is Critical", down.Message)
+	assert.Greater(t, down.PreviousDuration, time.Duration(0))
+
+	serviceDown := receive(t, ch)
+	assert.True(t, serviceDown.Aggregate)
+	assert.Empty(t, serviceDown.Dependency)
+	assert.Equal(t, heartbeat.StatusCritical, serviceDown.Status)
+
+	up := receive(t, ch)
+	assert.Equal(t, "cache", up.Dependency)
+	assert.Equal(t, heartbeat.StatusCritical, up.Previous)
+	assert.Equal(t, heartbeat.StatusOK, up.Status)
+	assert.False(t, up.Time.Before(down.Time))
+
+	serviceUp := receive(t, ch)
+	assert.True(t, serviceUp.Aggregate)
+	assert.Equal(t, heartbeat.StatusOK, serviceUp.Status)
+
+	select {
+	case tr := <-ch:
+		assert.Failf(t, "unexpected transition", "%+v", tr)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestStatusFeedCallbacks(t *testing.T) {
+	deps, status := switchableDeps()
+	feed := heartbeat.NewStatusFeed()
+
+	var first, second atomic.Int32
+	unsubscribeFirst := feed.Subscribe(func(heartbeat.Transition) { first.Add(1) })
+	defer feed.Subscribe(func(heartbeat.Transition) { second.Add(1) })()
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/health", heartbeat.NewHandler("unit-test", deps, heartbeat.WithObserver(feed)))
+
+	status.Store(int32(heartbeat.StatusWarning))
+	healthResponse(t, r)
+	assert.Eventually(t, func() bool { return first.Load() == 2 && second.Load() == 2 }, time.Second, time.Millisecond)
+
+	unsubscribeFirst()
+	status.Store(int32(heartbeat.StatusCritical))
+	healthResponse(t, r)
+	assert.Eventually(t, func() bool { return second.Load() == 4 }, time.Second, time.Millisecond)
+	assert.Equal(t, int32(2), first.Load())
+}
+
+func TestStatusFeedUnsubscribeClosesChannel(t *testing.T) {
+	feed := heartbeat.NewStatusFeed()
+	ch, unsubscribe := feed.SubscribeChan()
+	unsubscribe()
+	unsubscribe()
+
+	select {
+	case _, ok := <-ch:
+		assert.False(t, ok)
+	case <-time.After(time.Second):
+		assert.Fail(t, "channel not closed")
+	}
+}
+
+func TestStatusFeedFedByMonitor(t *testing.T) {
+	deps, status := switchableDeps()
+	feed := heartbeat.NewStatusFeed()
+	ch, unsubscribe := feed.SubscribeChan()
+	defer unsubscribe()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go heartbeat.NewMonitor("unit-test", deps, heartbeat.WithObserver(feed)).Run(ctx, 10*time.Millisecond)
+
+	status.Store(int32(heartbeat.StatusCritical))
+	down := receive(t, ch)
+	assert.False(t, down.Aggregate)
+	assert.Equal(t, "cache", down.Dependency)
+	assert.Equal(t, heartbeat.StatusCritical, down.Status)
+}
@@ -0,0 +1,158 @@
+package heartbeat
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// Transition is a change in the status of a dependency, or of a service as a
+// whole, between two check runs.
+type Transition struct {
+	Service string
+
+	// Aggregate is true for a change in the overall status of the service, in
+	// which case Dependency, Type and Message are empty.
+	Aggregate  bool
+	Dependency string
+	Type       string
+
+	Previous Status
+	Status   Status
+	Message  string
+
+	// Time is when the check run that observed the change completed, and
+	// Duration is how long the dependency check, or the whole run, took.
+	Time     time.Time
+	Duration time.Duration
+
+	// PreviousDuration is how long the previous status lasted. It is 0 when
+	// the first status observed is already unhealthy.
+	PreviousDuration time.Duration
+}
+
+// transitionTracker turns check runs into transitions. The first status
+// observed counts as a transition from NotSet unless it is OK or NotSet;
+// repeated identical statuses are not transitions.
+type transitionTracker struct {
+	mu      sync.Mutex
+	current map[string]statusSince
+}
+
+// statusSince records a status and when it was first observed.
+type statusSince struct {
+	status Status
+	since  time.Time
+}
+
+// track returns the transitions in the run: those of its dependencies in the
+// order of its results, followed by that of the service.
+func (tt *transitionTracker) track(run CheckRun) []Transition {
+	tt.mu.Lock()
+	defer tt.mu.Unlock()
+	if tt.current == nil {
+		tt.current = make(map[string]statusSince)
+	}
+
+	var transitions []Transition
+	for _, r := range run.Results {
+		t, ok := tt.observe(run.Service+"\x00"+r.Name+"\x00"+r.Type, r.Status, run.Time)
+		if !ok {
+			continue
+		}
+		t.Service = run.Service
+		t.Dependency = r.Name
+		t.Type = r.Type
+		t.Message = r.Message
+		t.Duration = time.Duration(r.RequestDuration * float64(time.Millisecond))
+		transitions = append(transitions, t)
+	}
+
+	if t, ok := tt.observe(run.Service, run.Status, run.Time); ok {
+		t.Service = run.Service
+		t.Aggregate = true
+		t.Duration = run.Duration
+		transitions = append(transitions, t)
+	}
+	return transitions
+}
+
+// observe records status under key at now and returns the transition, if any.
+func (tt *transitionTracker) observe(key string, status Status, now time.Time) (Transition, bool) {
+	last, seen := tt.current[key]
+	if seen && status == last.status {
+		return Transition{}, false
+	}
+	tt.current[key] = statusSince{status: status, since: now}
+
+	t := Transition{Previous: last.status, Status: status, Time: now}
+	if !seen {
+		return t, status.Severity() > StatusOK.Severity()
+	}
+	t.PreviousDuration = now.Sub(last.since)
+	return t, true
+}
+
+// WithLogger logs a structured record whenever the status of the service or of
+// one of its dependencies changes between check runs. Repeated identical
+// statuses are not logged, and neither is an initial OK or NotSet status.
+// Records are logged at Info level for OK, Warn for Warning and Error for
+// Unknown and Critical, with these attributes:
+//
+//   - service, and dependency and type for dependency records
+//   - previous_status and status
+//   - message, the dependency's Message
+//   - duration, the time the check run or dependency check took
+//   - previous_duration, how long the previous status lasted
+func WithLogger(logger *slog.Logger) Option {
+	return func(cfg *config) {
+		if logger != nil {
+			cfg.observers = append(cfg.observers, &transitionLog{logger: logger})
+		}
+	}
+}
+
+// transitionLog is an Observer that logs status changes.
+type transitionLog struct {
+	logger  *slog.Logger
+	tracker transitionTracker
+}
+
+// ObserveRun implements Observer.
+func (l *transitionLog) ObserveRun(run CheckRun) {
+	for _, t := range l.tracker.track(run) {
+		l.log(t)
+	}
+}
+
+func (l *transitionLog) log(t Transition) {
+	level := slog.LevelInfo
+	switch {
+	case t.Status.failing():
+		level = slog.LevelError
+	case t.Status == StatusWarning:
+		level = slog.LevelWarn
+	}
+
+	msg := "dependency status changed"
+	attrs := []slog.Attr{slog.String("service", t.Service)}
+	if t.Aggregate {
+		msg = "service status changed"
+	} else {
+		attrs = append(attrs,
+			slog.String("dependency", t.Dependency),
+			slog.String("type", t.Type),
+			slog.String("message", t.Message),
+		)
+	}
+	attrs = append(attrs,
+		slog.Duration("duration", t.Duration),
+		slog.String("previous_status", t.Previous.String()),
+		slog.String("status", t.Status.String()),
+	)
+	if t.PreviousDuration > 0 {
+		attrs = append(attrs, slog.Duration("previous_duration", t.PreviousDuration))
+	}
+	l.logger.LogAttrs(context.Background(), level, msg, attrs...)
+}
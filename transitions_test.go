@@ -0,0 +1,103 @@
+package heartbeat_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"sync/atomic"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/twistingmercury/heartbeat"
+)
+
+func logRecords(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	var records []map[string]any
+	dec := json.NewDecoder(buf)
+	for dec.More() {
+		var rec map[string]any
+		require.NoError(t, dec.Decode(&rec))
+		records = append(records, rec)
+	}
+	return records
+}
+
+func TestLoggerRecordsStatusTransitions(t *testing.T) {
+	var status atomic.Int32
+	status.Store(int32(heartbeat.StatusOK))
+	deps := []heartbeat.DependencyDescriptor{
+		{Name: "db", Type: "database", HandlerFunc: func() heartbeat.StatusResult {
+			s := heartbeat.Status(status.Load())
+			return heartbeat.StatusResult{Status: s, Message: "db is " + s.String()}
+		}},
+	}
+
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/health", heartbeat.NewHandler("unit-test", deps, heartbeat.WithLogger(logger)))
+
+	// An initial OK status is not logged, and neither are repeated ones.
+	healthResponse(t, r)
+	healthResponse(t, r)
+	assert.Empty(t, logRecords(t, &buf))
+
+	status.Store(int32(heartbeat.StatusCritical))
+	healthResponse(t, r)
+	healthResponse(t, r)
+	records := logRecords(t, &buf)
+	require.Len(t, records, 2)
+
+	dep := records[0]
+	assert.Equal(t, "ERROR", dep["level"])
+	assert.Equal(t, "dependency status changed", dep["msg"])
+	assert.Equal(t, "unit-test", dep["service"])
+	assert.Equal(t, "db", dep["dependency"])
+	assert.Equal(t, "database", dep["type"])
+	assert.Equal(t, "OK", dep["previous_status"])
+	assert.Equal(t, "Critical", dep["status"])
+	assert.Equal(t, "db is Critical", dep["message"])
+	assert.Contains(t, dep, "duration")
+	assert.Greater(t, dep["previous_duration"], float64(0))
+
+	svc := records[1]
+	assert.Equal(t, "service status changed", svc["msg"])
+	assert.Equal(t, "OK", svc["previous_status"])
+	assert.Equal(t, "Critical", svc["status"])
+	assert.NotContains(t, svc, "dependency")
+
+	status.Store(int32(heartbeat.StatusOK))
+	healthResponse(t, r)
+	records = logRecords(t, &buf)
+	require.Len(t, records, 2)
+	assert.Equal(t, "INFO", records[0]["level"])
+	assert.Equal(t, "Critical", records[0]["previous_status"])
+	assert.Equal(t, "OK", records[0]["status"])
+}
+
+func TestLoggerRecordsInitialFailure(t *testing.T) {
+	deps := []heartbeat.DependencyDescriptor{
+		{Name: "cache", Type: "redis", HandlerFunc: func() heartbeat.StatusResult {
+			return heartbeat.StatusResult{Status: heartbeat.StatusWarning, Message: "slow"}
+		}},
+	}
+
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/health", heartbeat.NewHandler("unit-test", deps, heartbeat.WithLogger(logger)))
+	healthResponse(t, r)
+
+	records := logRecords(t, &buf)
+	require.Len(t, records, 2)
+	assert.Equal(t, "WARN", records[0]["level"])
+	assert.Equal(t, "NotSet", records[0]["previous_status"])
+	assert.Equal(t, "Warning", records[0]["status"])
+	assert.NotContains(t, records[0], "previous_duration")
+}
@@ -0,0 +1,309 @@
+package heartbeat
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// UptimeWindows are the rolling windows over which Uptime reports availability.
+var UptimeWindows = []time.Duration{time.Hour, 24 * time.Hour, 7 * 24 * time.Hour, 30 * 24 * time.Hour}
+
+// Availability over the 1 hour window is counted in minute buckets, and over
+// the longer windows in hour buckets, so those windows move an hour at a time.
+const (
+	minuteBuckets = 60
+	hourBuckets   = 30 * 24
+)
+
+// SLO is an availability objective, such as 99.9% over 30 days.
+type SLO struct {
+	// Objective is the target availability as a fraction, such as 0.999.
+	Objective float64
+	// Window is one of the UptimeWindows. Defaults to 30 days.
+	Window time.Duration
+}
+
+// UptimeOptions configures an Uptime.
+type UptimeOptions struct {
+	// ServiceSLO is the objective for the overall status of the service.
+	ServiceSLO *SLO
+	// SLOs are the objectives of dependencies, by name.
+	SLOs map[string]SLO
+}
+
+// UptimeReport is the availability of a service and its dependencies.
+type UptimeReport struct {
+	Service      UptimeStats   `json:"service"`
+	Dependencies []UptimeStats `json:"dependencies"`
+}
+
+// UptimeStats is the availability of a service or a dependency.
+type UptimeStats struct {
+	Name         string               `json:"name"`
+	Type         string               `json:"type,omitempty"`
+	Availability []WindowAvailability `json:"availability"`
+	SLO          *SLOStatus           `json:"slo,omitempty"`
+}
+
+// WindowAvailability is the share of checks in a rolling window with an OK or
+// Warning status. Availability is nil when there were no checks in the window.
+type WindowAvailability struct {
+	Window       string   `json:"window"`
+	Checks       int      `json:"checks"`
+	Availability *float64 `json:"availability_percent,omitempty"`
+}
+
+// SLOStatus compares the availability over the window of an SLO with its
+// objective. ErrorBudgetRemaining is the share of the allowed failures not yet
+// used; it is negative once the objective is missed.
+type SLOStatus struct {
+	Objective            float64  `json:"objective_percent"`
+	Window               string   `json:"window"`
+	Availability         *float64 `json:"availability_percent,omitempty"`
+	ErrorBudgetRemaining float64  `json:"error_budget_remaining_percent"`
+	Met                  bool     `json:"met"`
+}
+
+// Uptime computes rolling availability of a service and its dependencies from
+// the results of its check runs, counting a check as available when its status
+// is OK or Warning. The service is also available with the NotSet status of a
+// service without dependencies. WithUptime feeds it the handler's runs, and
+// Handler serves the resulting report. An Uptime tracks the service of one
+// handler: the dependencies of a second one would be mixed in by name.
+type Uptime struct {
+	opts UptimeOptions
+
+	mu      sync.Mutex
+	service *availability
+	deps    map[string]*availability
+	now     func() time.Time
+}
+
+// NewUptime returns an Uptime without any recorded checks.
+func NewUptime(opts UptimeOptions) *Uptime {
+	return &Uptime{
+		opts:    opts,
+		service: newAvailability(""),
+		deps:    make(map[string]*availability),
+		now:     time.Now,
+	}
+}
+
+// ObserveRun implements Observer.
+func (u *Uptime) ObserveRun(run CheckRun) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.service.add(run.Time, available(run.Status) || run.Status == StatusNotSet)
+	for _, r := range run.Results {
+		u.record(r.Name, r.Type, run.Time, r.Status)
+	}
+}
+
+// record counts a check of the named dependency. The caller holds u.mu.
+func (u *Uptime) record(name, typ string, t time.Time, status Status) {
+	a, ok := u.deps[name]
+	if !ok {
+		a = newAvailability(typ)
+		u.deps[name] = a
+	}
+	a.typ = typ
+	a.add(t, available(status))
+}
+
+// Report returns the availability of the service, named after service, and of
+// its dependencies, sorted by name.
+func (u *Uptime) Report(service string) UptimeReport {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	now := u.now()
+	report := UptimeReport{
+		Service:      u.service.stats(service, now, u.opts.ServiceSLO),
+		Dependencies: make([]UptimeStats, 0, len(u.deps)),
+	}
+	for name, a := range u.deps {
+		var slo *SLO
+		if s, ok := u.opts.SLOs[name]; ok {
+			slo = &s
+		}
+		report.Dependencies = append(report.Dependencies, a.stats(name, now, slo))
+	}
+	sort.Slice(report.Dependencies, func(i, j int) bool {
+		return report.Dependencies[i].Name < report.Dependencies[j].Name
+	})
+	return report
+}
+
+// Handler returns a handler that serves the UptimeReport as JSON.
+//
+//	r.GET("/health/uptime", uptime.Handler("orders"))
+func (u *Uptime) Handler(service string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, u.Report(service))
+	}
+}
+
+// WithUptime records the handler's check results in the Uptime and, when
+// embed is true, includes its UptimeReport in the Response.
+func WithUptime(u *Uptime, embed bool) Option {
+	return func(cfg *config) {
+		if u == nil {
+			return
+		}
+		cfg.observers = append(cfg.observers, u)
+		if embed {
+			cfg.uptime = u
+		}
+	}
+}
+
+// availability counts the checks of a service or dependency in time buckets.
+type availability struct {
+	typ     string
+	minutes bucketRing
+	hours   bucketRing
+}
+
+func newAvailability(typ string) *availability {
+	return &availability{
+		typ:     typ,
+		minutes: newBucketRing(time.Minute, minuteBuckets),
+		hours:   newBucketRing(time.Hour, hourBuckets),
+	}
+}
+
+// available reports whether a check with the status counts as available.
+func available(status Status) bool {
+	return status == StatusOK || status == StatusWarning
+}
+
+func (a *availability) add(t time.Time, up bool) {
+	a.minutes.add(t, up)
+	a.hours.add(t, up)
+}
+
+// count returns the number of checks, and of available ones, in the window.
+func (a *availability) count(now time.Time, window time.Duration) (total, up int) {
+	if window <= time.Hour {
+		return a.minutes.sum(now, window)
+	}
+	return a.hours.sum(now, window)
+}
+
+func (a *availability) stats(name string, now time.Time, slo *SLO) UptimeStats {
+	s := UptimeStats{Name: name, Type: a.typ}
+	for _, w := range UptimeWindows {
+		total, up := a.count(now, w)
+		s.Availability = append(s.Availability, WindowAvailability{
+			Window:       windowName(w),
+			Checks:       total,
+			Availability: percentOf(up, total),
+		})
+	}
+	if slo == nil {
+		return s
+	}
+
+	window := slo.Window
+	if window == 0 {
+		window = UptimeWindows[len(UptimeWindows)-1]
+	}
+	total, up := a.count(now, window)
+	status := &SLOStatus{
+		Objective:            slo.Objective * 100,
+		Window:               windowName(window),
+		Availability:         percentOf(up, total),
+		ErrorBudgetRemaining: 100,
+		Met:                  true,
+	}
+	if total > 0 {
+		failed := float64(total-up) / float64(total)
+		if budget := 1 - slo.Objective; budget > 0 {
+			status.ErrorBudgetRemaining = (1 - failed/budget) * 100
+		} else if failed > 0 {
+			status.ErrorBudgetRemaining = -100
+		}
+		status.Met = float64(up)/float64(total) >= slo.Objective
+	}
+	s.SLO = status
+	return s
+}
+
+func percentOf(n, total int) *float64 {
+	if total == 0 {
+		return nil
+	}
+	p := float64(n) / float64(total) * 100
+	return &p
+}
+
+// windowName formats a window as a number of days, hours or minutes, such as
+// "7d", "24h" or "30m".
+func windowName(d time.Duration) string {
+	const day = 24 * time.Hour
+	switch {
+	case d >= 2*day && d%day == 0:
+		return strconv.Itoa(int(d/day)) + "d"
+	case d%time.Hour == 0:
+		return strconv.Itoa(int(d/time.Hour)) + "h"
+	case d%time.Minute == 0:
+		return strconv.Itoa(int(d/time.Minute)) + "m"
+	default:
+		return d.String()
+	}
+}
+
+// bucketRing counts checks in fixed-width time buckets, keeping the most recent ones.
+type bucketRing struct {
+	width   time.Duration
+	buckets []bucket
+}
+
+type bucket struct {
+	index int64 // start of the bucket in widths since the Unix epoch
+	total int
+	up    int
+}
+
+func newBucketRing(width time.Duration, size int) bucketRing {
+	return bucketRing{width: width, buckets: make([]bucket, size)}
+}
+
+func (r *bucketRing) indexOf(t time.Time) int64 {
+	return t.UnixNano() / int64(r.width)
+}
+
+func (r *bucketRing) add(t time.Time, up bool) {
+	idx := r.indexOf(t)
+	b := &r.buckets[idx%int64(len(r.buckets))]
+	if b.index != idx {
+		if b.index > idx {
+			// Older than anything the ring keeps.
+			return
+		}
+		*b = bucket{index: idx}
+	}
+	b.total++
+	if up {
+		b.up++
+	}
+}
+
+// sum counts the checks in the buckets that start within window of now,
+// including the bucket now falls in.
+func (r *bucketRing) sum(now time.Time, window time.Duration) (total, up int) {
+	last := r.indexOf(now)
+	first := last - int64(window/r.width) + 1
+	for _, b := range r.buckets {
+		if b.index >= first && b.index <= last {
+			total += b.total
+			up += b.up
+		}
+	}
+	return total, up
+}
@@ -0,0 +1,177 @@
+package heartbeat_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/twistingmercury/heartbeat"
+)
+
+var uptimeNow = time.Date(2025, 1, 31, 12, 0, 0, 0, time.UTC)
+
+// observeCheck records a run of a single "payments" dependency at the given age.
+func observeCheck(u *heartbeat.Uptime, age time.Duration, status heartbeat.Status) {
+	u.ObserveRun(heartbeat.CheckRun{
+		Service: "unit-test",
+		Status:  status,
+		Time:    uptimeNow.Add(-age),
+		Results: []heartbeat.StatusResult{{Name: "payments", Type: "HTTP", Status: status}},
+	})
+}
+
+func newUptime(opts heartbeat.UptimeOptions) *heartbeat.Uptime {
+	u := heartbeat.NewUptime(opts)
+	u.SetClock(func() time.Time { return uptimeNow })
+	return u
+}
+
+func windowAvailability(t *testing.T, s heartbeat.UptimeStats, window string) heartbeat.WindowAvailability {
+	for _, w := range s.Availability {
+		if w.Window == window {
+			return w
+		}
+	}
+	require.FailNow(t, "window not reported", window)
+	return heartbeat.WindowAvailability{}
+}
+
+func TestUptimeRollingWindows(t *testing.T) {
+	u := newUptime(heartbeat.UptimeOptions{})
+	observeCheck(u, time.Minute, heartbeat.StatusOK)
+	observeCheck(u, 2*time.Minute, heartbeat.StatusWarning)
+	observeCheck(u, 3*time.Minute, heartbeat.StatusCritical)
+	observeCheck(u, 4*time.Minute, heartbeat.StatusOK)
+	observeCheck(u, 5*time.Hour, heartbeat.StatusCritical)
+	observeCheck(u, 3*24*time.Hour, heartbeat.StatusOK)
+	observeCheck(u, 20*24*time.Hour, heartbeat.StatusCritical)
+	observeCheck(u, 40*24*time.Hour, heartbeat.StatusCritical) // outside every window
+
+	report := u.Report("unit-test")
+	require.Len(t, report.Dependencies, 1)
+	dep := report.Dependencies[0]
+	assert.Equal(t, "payments", dep.Name)
+	assert.Equal(t, "HTTP", dep.Type)
+	assert.Nil(t, dep.SLO)
+
+	for window, want := range map[string]struct {
+		checks       int
+		availability float64
+	}{
+		"1h":  {4, 75},
+		"24h": {5, 60},
+		"7d":  {6, 100 * 4.0 / 6},
+		"30d": {7, 100 * 4.0 / 7},
+	} {
+		w := windowAvailability(t, dep, window)
+		assert.Equal(t, want.checks, w.Checks, window)
+		require.NotNil(t, w.Availability, window)
+		assert.InDelta(t, want.availability, *w.Availability, 1e-9, window)
+	}
+
+	assert.Equal(t, "unit-test", report.Service.Name)
+	assert.Equal(t, 4, windowAvailability(t, report.Service, "1h").Checks)
+}
+
+func TestUptimeWithoutChecks(t *testing.T) {
+	u := newUptime(heartbeat.UptimeOptions{ServiceSLO: &heartbeat.SLO{Objective: 0.99}})
+	report := u.Report("unit-test")
+	assert.Empty(t, report.Dependencies)
+
+	w := windowAvailability(t, report.Service, "1h")
+	assert.Zero(t, w.Checks)
+	assert.Nil(t, w.Availability)
+	require.NotNil(t, report.Service.SLO)
+	assert.True(t, report.Service.SLO.Met)
+	assert.Equal(t, float64(100), report.Service.SLO.ErrorBudgetRemaining)
+}
+
+func TestUptimeNotSetCountsAsUpOnlyForService(t *testing.T) {
+	u := newUptime(heartbeat.UptimeOptions{})
+	observeCheck(u, time.Minute, heartbeat.StatusNotSet)
+	u.ObserveRun(heartbeat.CheckRun{Service: "unit-test", Status: heartbeat.StatusNotSet, Time: uptimeNow.Add(-time.Minute)})
+
+	report := u.Report("unit-test")
+	require.Len(t, report.Dependencies, 1)
+	dep := windowAvailability(t, report.Dependencies[0], "1h")
+	require.NotNil(t, dep.Availability)
+	assert.Zero(t, *dep.Availability)
+
+	svc := windowAvailability(t, report.Service, "1h")
+	assert.Equal(t, 2, svc.Checks)
+	require.NotNil(t, svc.Availability)
+	assert.Equal(t, float64(100), *svc.Availability)
+}
+
+func TestUptimeSLOErrorBudget(t *testing.T) {
+	u := newUptime(heartbeat.UptimeOptions{
+		SLOs: map[string]heartbeat.SLO{"payments": {Objective: 0.9, Window: 24 * time.Hour}},
+	})
+	for i := range 20 {
+		status := heartbeat.StatusOK
+		if i == 0 {
+			status = heartbeat.StatusCritical
+		}
+		observeCheck(u, time.Duration(i)*time.Minute, status)
+	}
+
+	slo := u.Report("unit-test").Dependencies[0].SLO
+	require.NotNil(t, slo)
+	assert.Equal(t, float64(90), slo.Objective)
+	assert.Equal(t, "24h", slo.Window)
+	assert.InDelta(t, 95, *slo.Availability, 1e-9)
+	assert.InDelta(t, 50, slo.ErrorBudgetRemaining, 1e-9) // half of the allowed 10% used
+	assert.True(t, slo.Met)
+
+	observeCheck(u, 0, heartbeat.StatusCritical)
+	observeCheck(u, 0, heartbeat.StatusCritical)
+	slo = u.Report("unit-test").Dependencies[0].SLO
+	assert.False(t, slo.Met)
+	assert.Less(t, slo.ErrorBudgetRemaining, float64(0))
+}
+
+func TestUptimeHandlerAndResponse(t *testing.T) {
+	deps, _ := switchableDeps()
+	u := heartbeat.NewUptime(heartbeat.UptimeOptions{ServiceSLO: &heartbeat.SLO{Objective: 0.999, Window: 7 * 24 * time.Hour}})
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/health", heartbeat.NewHandler("unit-test", deps, heartbeat.WithUptime(u, true)))
+	r.GET("/uptime", u.Handler("unit-test"))
+
+	hb := healthResponse(t, r)
+	require.NotNil(t, hb.Uptime)
+	assert.Len(t, hb.Uptime.Dependencies, 2)
+	assert.Equal(t, 1, windowAvailability(t, hb.Uptime.Service, "1h").Checks)
+
+	resp := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/uptime", nil)
+	r.ServeHTTP(resp, req)
+	require.Equal(t, http.StatusOK, resp.Code)
+
+	var report heartbeat.UptimeReport
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &report))
+	assert.Equal(t, "unit-test", report.Service.Name)
+	require.NotNil(t, report.Service.SLO)
+	assert.Equal(t, "7d", report.Service.SLO.Window)
+	assert.Equal(t, "cache", report.Dependencies[0].Name)
+	assert.Equal(t, "db", report.Dependencies[1].Name)
+}
+
+func TestWithUptimeWithoutEmbedding(t *testing.T) {
+	deps, _ := switchableDeps()
+	u := heartbeat.NewUptime(heartbeat.UptimeOptions{})
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/health", heartbeat.NewHandler("unit-test", deps, heartbeat.WithUptime(u, false)))
+
+	hb := healthResponse(t, r)
+	assert.Nil(t, hb.Uptime)
+	assert.Len(t, u.Report("unit-test").Dependencies, 2)
+}
@@ -0,0 +1,480 @@
+package heartbeat
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+)
+
+// WebhookFormat selects the payload a Webhook posts.
+type WebhookFormat int
+
+const (
+	// WebhookGeneric posts a WebhookPayload as JSON.
+	WebhookGeneric WebhookFormat = iota
+	// WebhookSlack posts a Slack incoming webhook message.
+	WebhookSlack
+	// WebhookCloudEvents posts a CloudEvents 1.0 event in structured mode,
+	// with a WebhookPayload as its data.
+	WebhookCloudEvents
+)
+
+const (
+	// SignatureHeader carries the HMAC-SHA256 of the request body, keyed with
+	// the Webhook's Secret, as "sha256=" followed by the hex digest.
+	SignatureHeader = "X-Heartbeat-Signature"
+
+	// CloudEventType is the type of the CloudEvents posted by webhooks.
+	CloudEventType = "io.github.twistingmercury.heartbeat.transition"
+
+	defaultWebhookTimeout = 10 * time.Second
+	defaultRateWindow     = time.Minute
+)
+
+// Webhook is an endpoint that is notified of status transitions.
+type Webhook struct {
+	URL    string
+	Format WebhookFormat
+
+	// Secret, when set, signs every request in the SignatureHeader.
+	Secret string
+
+	// Dependencies routes the transitions of only the named dependencies to
+	// this webhook; empty routes all of them. ExcludeAggregate stops the
+	// transitions of the overall service status.
+	Dependencies     []string
+	ExcludeAggregate bool
+
+	// RateLimit caps the notifications for each dependency, and for the
+	// overall status, to RateLimit per RateWindow, which defaults to a
+	// minute. The latest transition over the limit is held back and sent once
+	// the window allows, unless a later one is sent first; the transitions
+	// dropped in its favour are counted in the Suppressed field of the next
+	// payload sent. Zero disables rate limiting.
+	RateLimit  int
+	RateWindow time.Duration
+
+	// Retry retries failed deliveries: connection errors, timeouts, HTTP 429
+	// and 5xx responses. Its On field is ignored.
+	Retry *RetryPolicy
+
+	// Timeout bounds each delivery attempt. Defaults to 10 seconds.
+	Timeout time.Duration
+
+	// Client sends the requests. Defaults to http.DefaultClient.
+	Client *http.Client
+
+	// OnError is called with transitions that could not be delivered.
+	OnError func(Transition, error)
+}
+
+// WebhookPayload is the JSON body of WebhookGeneric requests and the data of
+// WebhookCloudEvents events.
+type WebhookPayload struct {
+	Service          string    `json:"service"`
+	Aggregate        bool      `json:"aggregate"`
+	Dependency       string    `json:"dependency,omitempty"`
+	Type             string    `json:"type,omitempty"`
+	PreviousStatus   Status    `json:"previous_status"`
+	Status           Status    `json:"status"`
+	Message          string    `json:"message,omitempty"`
+	Time             time.Time `json:"time"`
+	Duration         float64   `json:"duration_ms"`
+	PreviousDuration float64   `json:"previous_duration_ms,omitempty"`
+	Suppressed       int       `json:"suppressed,omitempty"`
+}
+
+// Notifier posts status transitions to webhooks. It only learns of the check
+// runs it observes, so register it with a Monitor through WithObserver to be
+// notified even when nothing calls the health endpoint, and Close it once the
+// monitor stops:
+//
+//	notifier := heartbeat.NewNotifier(heartbeat.Webhook{URL: slackURL, Format: heartbeat.WebhookSlack})
+//	defer notifier.Close()
+//	m := heartbeat.NewMonitor("orders", deps, heartbeat.WithObserver(notifier))
+//	go m.Run(ctx, 30*time.Second)
+//	r.GET("/health", m.Handler())
+//
+// The same status changes that WithLogger logs are posted. Each webhook is
+// notified on its own goroutine, in the order the transitions occurred, so a
+// slow or failing endpoint doesn't hold up the checks or the other webhooks.
+type Notifier struct {
+	tracker transitionTracker
+
+	mu    sync.Mutex
+	hooks []*webhookSender
+}
+
+// NewNotifier returns a Notifier for the given webhooks.
+func NewNotifier(webhooks ...Webhook) *Notifier {
+	n := &Notifier{}
+	for _, w := range webhooks {
+		if w.RateLimit > 0 && w.RateWindow <= 0 {
+			w.RateWindow = defaultRateWindow
+		}
+		ws := &webhookSender{
+			hook:       w,
+			sent:       make(map[string][]time.Time),
+			suppressed: make(map[string]int),
+			latest:     make(map[string]time.Time),
+			held:       make(map[string]Transition),
+			timers:     make(map[string]*time.Timer),
+		}
+		ws.ctx, ws.cancel = context.WithCancel(context.Background())
+		ws.sub = newSubscriber(ws.notify)
+		go ws.sub.run()
+		n.hooks = append(n.hooks, ws)
+	}
+	return n
+}
+
+// ObserveRun implements Observer.
+func (n *Notifier) ObserveRun(run CheckRun) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	for _, t := range n.tracker.track(run) {
+		for _, ws := range n.hooks {
+			if ws.routes(t) {
+				ws.sub.push(t)
+			}
+		}
+	}
+}
+
+// Close stops notifying the webhooks. Transitions not yet delivered, including
+// those held back by a rate limit, are discarded, and requests in flight are
+// cancelled, so that no webhook is called once Close returns.
+func (n *Notifier) Close() {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	for _, ws := range n.hooks {
+		ws.stop()
+	}
+}
+
+// webhookSender delivers the transitions routed to a webhook. Its state is
+// only used by the goroutine of its subscriber, except for that guarded by mu,
+// which the timers that release held transitions share.
+type webhookSender struct {
+	hook   Webhook
+	sub    *subscriber
+	ctx    context.Context // cancelled by stop
+	cancel context.CancelFunc
+
+	sent       map[string][]time.Time
+	suppressed map[string]int
+	latest     map[string]time.Time // time of the latest transition considered
+
+	mu     sync.Mutex
+	closed bool
+	held   map[string]Transition // latest transition over the rate limit
+	timers map[string]*time.Timer
+}
+
+// stop discards the held transitions and stops their timers, then stops the
+// subscriber and cancels the request in flight, if any.
+func (ws *webhookSender) stop() {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	if ws.closed {
+		return
+	}
+	ws.closed = true
+	for key, timer := range ws.timers {
+		timer.Stop()
+		delete(ws.timers, key)
+		delete(ws.held, key)
+	}
+	close(ws.sub.done)
+	ws.cancel()
+}
+
+// stopped reports whether stop has been called.
+func (ws *webhookSender) stopped() bool {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	return ws.closed
+}
+
+func (ws *webhookSender) routes(t Transition) bool {
+	if t.Aggregate {
+		return !ws.hook.ExcludeAggregate
+	}
+	return len(ws.hook.Dependencies) == 0 || slices.Contains(ws.hook.Dependencies, t.Dependency)
+}
+
+// rateKey identifies the dependency, or the overall status, of a transition.
+func rateKey(t Transition) string {
+	if t.Aggregate {
+		return "\x00"
+	}
+	return t.Dependency
+}
+
+// allow applies the rate limit at now. When the notification is over the
+// limit, it returns when the window allows the next one.
+func (ws *webhookSender) allow(key string, now time.Time) (time.Time, bool) {
+	sent := ws.sent[key]
+	for len(sent) > 0 && now.Sub(sent[0]) >= ws.hook.RateWindow {
+		sent = sent[1:]
+	}
+	if len(sent) >= ws.hook.RateLimit {
+		ws.sent[key] = sent
+		return sent[0].Add(ws.hook.RateWindow), false
+	}
+	ws.sent[key] = append(sent, now)
+	return time.Time{}, true
+}
+
+// hold keeps t, replacing any transition held before it, and releases it to
+// the subscriber at the given time.
+func (ws *webhookSender) hold(key string, t Transition, at time.Time) {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	if ws.closed {
+		return
+	}
+	if _, ok := ws.held[key]; ok {
+		ws.suppressed[key]++
+		ws.held[key] = t
+		return // its timer is already running
+	}
+	ws.held[key] = t
+
+	// The timer is stored while mu is held, before its function can run.
+	var timer *time.Timer
+	timer = time.AfterFunc(time.Until(at), func() {
+		ws.mu.Lock()
+		if ws.closed || ws.timers[key] != timer {
+			// Stopped too late, by stop or release.
+			ws.mu.Unlock()
+			return
+		}
+		t := ws.held[key]
+		delete(ws.held, key)
+		delete(ws.timers, key)
+		ws.mu.Unlock()
+		ws.sub.push(t)
+	})
+	ws.timers[key] = timer
+}
+
+// release drops the transition held for key, superseded by a later one.
+func (ws *webhookSender) release(key string) {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	if _, ok := ws.held[key]; ok {
+		delete(ws.held, key)
+		ws.suppressed[key]++
+	}
+	if timer, ok := ws.timers[key]; ok {
+		timer.Stop()
+		delete(ws.timers, key)
+	}
+}
+
+func (ws *webhookSender) notify(t Transition) {
+	var suppressed int
+	if ws.hook.RateLimit > 0 {
+		key := rateKey(t)
+		if t.Time.Before(ws.latest[key]) {
+			// A held transition released after a later one was sent.
+			ws.suppressed[key]++
+			return
+		}
+		ws.latest[key] = t.Time
+
+		if next, ok := ws.allow(key, time.Now()); !ok {
+			ws.hold(key, t, next)
+			return
+		}
+		ws.release(key)
+		suppressed = ws.suppressed[key]
+		delete(ws.suppressed, key)
+	}
+
+	body, contentType, err := webhookBody(ws.hook.Format, t, suppressed)
+	if err == nil {
+		err = ws.deliver(body, contentType)
+	}
+	if err != nil && ws.hook.OnError != nil && !ws.stopped() {
+		ws.hook.OnError(t, err)
+	}
+}
+
+// deliver posts the body, retrying as the webhook's policy allows.
+func (ws *webhookSender) deliver(body []byte, contentType string) error {
+	attempts := 1
+	if ws.hook.Retry != nil && ws.hook.Retry.Attempts > 1 {
+		attempts = ws.hook.Retry.Attempts
+	}
+
+	var err error
+	for attempt := 1; ; attempt++ {
+		if ws.stopped() {
+			return nil // the Notifier was closed
+		}
+		var retry bool
+		retry, err = ws.post(body, contentType)
+		if err == nil || !retry || attempt >= attempts {
+			return err
+		}
+
+		timer := time.NewTimer(ws.hook.Retry.delay(attempt))
+		select {
+		case <-timer.C:
+		case <-ws.sub.done:
+			timer.Stop()
+			return err
+		}
+	}
+}
+
+// post makes a single delivery attempt and reports whether a failure may be retried.
+func (ws *webhookSender) post(body []byte, contentType string) (retry bool, err error) {
+	timeout := ws.hook.Timeout
+	if timeout == 0 {
+		timeout = defaultWebhookTimeout
+	}
+	ctx, cancel := context.WithTimeout(ws.ctx, timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, ws.hook.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, fmt.Errorf("invalid webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", contentType)
+	if ws.hook.Secret != "" {
+		req.Header.Set(SignatureHeader, Sign(ws.hook.Secret, body))
+	}
+
+	client := ws.hook.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return true, fmt.Errorf("webhook request failed: %w", err)
+	}
+	defer func() {
+		_ = resp.Body.Close() // Error intentionally ignored - only the status code is used
+	}()
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+	err = fmt.Errorf("webhook returned %s", resp.Status)
+	return resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests, err
+}
+
+// Sign returns the SignatureHeader value for body, so that receivers can
+// verify a webhook request with hmac.Equal.
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// NewWebhookPayload returns the payload describing the transition.
+func NewWebhookPayload(t Transition) WebhookPayload {
+	return WebhookPayload{
+		Service:          t.Service,
+		Aggregate:        t.Aggregate,
+		Dependency:       t.Dependency,
+		Type:             t.Type,
+		PreviousStatus:   t.Previous,
+		Status:           t.Status,
+		Message:          t.Message,
+		Time:             t.Time.UTC(),
+		Duration:         float64(t.Duration.Microseconds()) / 1000,
+		PreviousDuration: float64(t.PreviousDuration.Microseconds()) / 1000,
+	}
+}
+
+func webhookBody(format WebhookFormat, t Transition, suppressed int) ([]byte, string, error) {
+	payload := NewWebhookPayload(t)
+	payload.Suppressed = suppressed
+
+	switch format {
+	case WebhookSlack:
+		body, err := json.Marshal(map[string]string{"text": slackText(t, suppressed)})
+		return body, "application/json", err
+	case WebhookCloudEvents:
+		subject := t.Dependency
+		if t.Aggregate {
+			subject = t.Service
+		}
+		body, err := json.Marshal(cloudEvent{
+			SpecVersion:     "1.0",
+			ID:              eventID(),
+			Source:          "heartbeat/" + t.Service,
+			Type:            CloudEventType,
+			Subject:         subject,
+			Time:            payload.Time,
+			DataContentType: "application/json",
+			Data:            payload,
+		})
+		return body, "application/cloudevents+json", err
+	default:
+		body, err := json.Marshal(payload)
+		return body, "application/json", err
+	}
+}
+
+// cloudEvent is a CloudEvents 1.0 event in the JSON event format.
+type cloudEvent struct {
+	SpecVersion     string         `json:"specversion"`
+	ID              string         `json:"id"`
+	Source          string         `json:"source"`
+	Type            string         `json:"type"`
+	Subject         string         `json:"subject,omitempty"`
+	Time            time.Time      `json:"time"`
+	DataContentType string         `json:"datacontenttype"`
+	Data            WebhookPayload `json:"data"`
+}
+
+func eventID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// slackText formats the transition as a Slack message, for example
+// ":red_circle: *orders*: redis (cache) is Critical, was OK for 5m0s: connection refused".
+func slackText(t Transition, suppressed int) string {
+	emoji := ":large_green_circle:"
+	switch {
+	case t.Status.failing():
+		emoji = ":red_circle:"
+	case t.Status == StatusWarning:
+		emoji = ":large_yellow_circle:"
+	}
+
+	subject := "service"
+	if !t.Aggregate {
+		subject = t.Dependency
+		if t.Type != "" {
+			subject += " (" + t.Type + ")"
+		}
+	}
+	text := fmt.Sprintf("%s *%s*: %s is %s", emoji, t.Service, subject, t.Status)
+	if t.PreviousDuration > 0 {
+		text += fmt.Sprintf(", was %s for %v", t.Previous, t.PreviousDuration.Round(time.Second))
+	}
+	if t.Message != "" {
+		text += ": " + t.Message
+	}
+	if suppressed > 0 {
+		text += fmt.Sprintf(" (%d earlier changes suppressed)", suppressed)
+	}
+	return text
+}
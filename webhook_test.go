@@ -0,0 +1,283 @@
+package heartbeat_test
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/twistingmercury/heartbeat"
+)
+
+type webhookRequest struct {
+	header http.Header
+	body   []byte
+}
+
+// webhookServer records the requests it receives, answering with the given
+// status codes in turn and 204 once they are used up.
+func webhookServer(t *testing.T, codes ...int) (*httptest.Server, func() []webhookRequest) {
+	var mu sync.Mutex
+	var requests []webhookRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		requests = append(requests, webhookRequest{header: r.Header, body: body})
+		code := http.StatusNoContent
+		if len(requests) <= len(codes) {
+			code = codes[len(requests)-1]
+		}
+		mu.Unlock()
+		w.WriteHeader(code)
+	}))
+	t.Cleanup(srv.Close)
+
+	return srv, func() []webhookRequest {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]webhookRequest(nil), requests...)
+	}
+}
+
+func waitForRequests(t *testing.T, requests func() []webhookRequest, n int) []webhookRequest {
+	t.Helper()
+	require.Eventually(t, func() bool { return len(requests()) >= n }, 2*time.Second, time.Millisecond)
+	return requests()
+}
+
+func TestWebhookGenericPayloadIsSigned(t *testing.T) {
+	srv, requests := webhookServer(t)
+	deps, status := switchableDeps()
+	n := heartbeat.NewNotifier(heartbeat.Webhook{URL: srv.URL, Secret: "s3cret", ExcludeAggregate: true})
+	defer n.Close()
+	r := healthHandler(deps, heartbeat.WithObserver(n))
+
+	healthResponse(t, r)
+	status.Store(int32(heartbeat.StatusCritical))
+	healthResponse(t, r)
+
+	got := waitForRequests(t, requests, 1)
+	require.Len(t, got, 1)
+	assert.Equal(t, "application/json", got[0].header.Get("Content-Type"))
+	assert.Equal(t, heartbeat.Sign("s3cret", got[0].body), got[0].header.Get(heartbeat.SignatureHeader))
+
+	var payload heartbeat.WebhookPayload
+	require.NoError(t, json.Unmarshal(got[0].body, &payload))
+	assert.Equal(t, "unit-test", payload.Service)
+	assert.Equal(t, "cache", payload.Dependency)
+	assert.Equal(t, "redis", payload.Type)
+	assert.Equal(t, heartbeat.StatusOK, payload.PreviousStatus)
+	assert.Equal(t, heartbeat.StatusCritical, payload.Status)
+	assert.Equal(t, "cache is Critical", payload.Message)
+}
+
+func TestWebhookSlackAndCloudEventsFormats(t *testing.T) {
+	slack, slackRequests := webhookServer(t)
+	events, eventRequests := webhookServer(t)
+	deps, status := switchableDeps()
+	n := heartbeat.NewNotifier(
+		heartbeat.Webhook{URL: slack.URL, Format: heartbeat.WebhookSlack, ExcludeAggregate: true},
+		heartbeat.Webhook{URL: events.URL, Format: heartbeat.WebhookCloudEvents, ExcludeAggregate: true},
+	)
+	defer n.Close()
+	r := healthHandler(deps, heartbeat.WithObserver(n))
+
+	status.Store(int32(heartbeat.StatusWarning))
+	healthResponse(t, r)
+
+	got := waitForRequests(t, slackRequests, 1)
+	var msg map[string]string
+	require.NoError(t, json.Unmarshal(got[0].body, &msg))
+	assert.Equal(t, ":large_yellow_circle: *unit-test*: cache (redis) is Warning: cache is Warning", msg["text"])
+
+	got = waitForRequests(t, eventRequests, 1)
+	assert.Equal(t, "application/cloudevents+json", got[0].header.Get("Content-Type"))
+	var event struct {
+		SpecVersion string                   `json:"specversion"`
+		ID          string                   `json:"id"`
+		Source      string                   `json:"source"`
+		Type        string                   `json:"type"`
+		Subject     string                   `json:"subject"`
+		Data        heartbeat.WebhookPayload `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(got[0].body, &event))
+	assert.Equal(t, "1.0", event.SpecVersion)
+	assert.NotEmpty(t, event.ID)
+	assert.Equal(t, "heartbeat/unit-test", event.Source)
+	assert.Equal(t, heartbeat.CloudEventType, event.Type)
+	assert.Equal(t, "cache", event.Subject)
+	assert.Equal(t, heartbeat.StatusWarning, event.Data.Status)
+}
+
+func TestWebhookRoutesByDependency(t *testing.T) {
+	dbHook, dbRequests := webhookServer(t)
+	cacheHook, cacheRequests := webhookServer(t)
+	deps, status := switchableDeps()
+	n := heartbeat.NewNotifier(
+		heartbeat.Webhook{URL: dbHook.URL, Dependencies: []string{"db"}, ExcludeAggregate: true},
+		heartbeat.Webhook{URL: cacheHook.URL, Dependencies: []string{"cache"}},
+	)
+	defer n.Close()
+	r := healthHandler(deps, heartbeat.WithObserver(n))
+
+	status.Store(int32(heartbeat.StatusCritical))
+	healthResponse(t, r)
+
+	got := waitForRequests(t, cacheRequests, 2)
+	assert.Len(t, got, 2) // the cache transition and the aggregate one
+	time.Sleep(20 * time.Millisecond)
+	assert.Empty(t, dbRequests())
+}
+
+func TestWebhookRetriesServerErrors(t *testing.T) {
+	srv, requests := webhookServer(t, http.StatusBadGateway, http.StatusServiceUnavailable)
+	deps, status := switchableDeps()
+	n := heartbeat.NewNotifier(heartbeat.Webhook{
+		URL:              srv.URL,
+		ExcludeAggregate: true,
+		Retry:            &heartbeat.RetryPolicy{Attempts: 3, Backoff: time.Millisecond},
+	})
+	defer n.Close()
+	r := healthHandler(deps, heartbeat.WithObserver(n))
+
+	status.Store(int32(heartbeat.StatusCritical))
+	healthResponse(t, r)
+	assert.Len(t, waitForRequests(t, requests, 3), 3)
+}
+
+func TestWebhookReportsUndeliveredTransitions(t *testing.T) {
+	srv, requests := webhookServer(t, http.StatusBadRequest)
+	deps, status := switchableDeps()
+	var failures atomic.Int32
+	n := heartbeat.NewNotifier(heartbeat.Webhook{
+		URL:              srv.URL,
+		ExcludeAggregate: true,
+		Retry:            &heartbeat.RetryPolicy{Attempts: 3, Backoff: time.Millisecond},
+		OnError: func(tr heartbeat.Transition, err error) {
+			assert.Equal(t, "cache", tr.Dependency)
+			assert.ErrorContains(t, err, "400 Bad Request")
+			failures.Add(1)
+		},
+	})
+	defer n.Close()
+	r := healthHandler(deps, heartbeat.WithObserver(n))
+
+	status.Store(int32(heartbeat.StatusCritical))
+	healthResponse(t, r)
+	require.Eventually(t, func() bool { return failures.Load() == 1 }, time.Second, time.Millisecond)
+	assert.Len(t, requests(), 1) // client errors are not retried
+}
+
+func TestWebhookRateLimitsFlappingDependency(t *testing.T) {
+	srv, requests := webhookServer(t)
+	deps, status := switchableDeps()
+	n := heartbeat.NewNotifier(heartbeat.Webhook{
+		URL:              srv.URL,
+		ExcludeAggregate: true,
+		RateLimit:        2,
+		RateWindow:       50 * time.Millisecond,
+	})
+	defer n.Close()
+	r := healthHandler(deps, heartbeat.WithObserver(n))
+
+	for _, s := range []heartbeat.Status{heartbeat.StatusCritical, heartbeat.StatusOK, heartbeat.StatusCritical, heartbeat.StatusOK} {
+		status.Store(int32(s))
+		healthResponse(t, r)
+	}
+	assert.Len(t, waitForRequests(t, requests, 2), 2)
+
+	// The latest transition over the limit is sent once the window reopens.
+	got := waitForRequests(t, requests, 3)
+	require.Len(t, got, 3)
+	var payload heartbeat.WebhookPayload
+	require.NoError(t, json.Unmarshal(got[2].body, &payload))
+	assert.Equal(t, heartbeat.StatusOK, payload.Status)
+	assert.Equal(t, 1, payload.Suppressed)
+
+	time.Sleep(60 * time.Millisecond)
+	status.Store(int32(heartbeat.StatusCritical))
+	healthResponse(t, r)
+
+	got = waitForRequests(t, requests, 4)
+	require.Len(t, got, 4)
+	payload = heartbeat.WebhookPayload{}
+	require.NoError(t, json.Unmarshal(got[3].body, &payload))
+	assert.Equal(t, heartbeat.StatusCritical, payload.Status)
+	assert.Zero(t, payload.Suppressed)
+}
+
+func TestWebhookRateLimitDefaultsWindow(t *testing.T) {
+	srv, requests := webhookServer(t)
+	deps, status := switchableDeps()
+	n := heartbeat.NewNotifier(heartbeat.Webhook{URL: srv.URL, ExcludeAggregate: true, RateLimit: 1})
+	defer n.Close()
+	r := healthHandler(deps, heartbeat.WithObserver(n))
+
+	for _, s := range []heartbeat.Status{heartbeat.StatusCritical, heartbeat.StatusOK, heartbeat.StatusCritical} {
+		status.Store(int32(s))
+		healthResponse(t, r)
+	}
+	waitForRequests(t, requests, 1)
+	time.Sleep(50 * time.Millisecond)
+	assert.Len(t, requests(), 1)
+}
+
+func TestNotifierCloseDiscardsHeldTransitions(t *testing.T) {
+	srv, requests := webhookServer(t)
+	deps, status := switchableDeps()
+	n := heartbeat.NewNotifier(heartbeat.Webhook{
+		URL:              srv.URL,
+		ExcludeAggregate: true,
+		RateLimit:        1,
+		RateWindow:       50 * time.Millisecond,
+	})
+	r := healthHandler(deps, heartbeat.WithObserver(n))
+
+	for _, s := range []heartbeat.Status{heartbeat.StatusCritical, heartbeat.StatusOK} {
+		status.Store(int32(s))
+		healthResponse(t, r)
+	}
+	waitForRequests(t, requests, 1)
+	time.Sleep(10 * time.Millisecond) // let the second transition be held
+	n.Close()
+
+	// The held transition would have been released once the window reopened.
+	time.Sleep(100 * time.Millisecond)
+	assert.Len(t, requests(), 1)
+}
+
+func TestNotifierCloseCancelsDelivery(t *testing.T) {
+	started := make(chan struct{})
+	var cancelled atomic.Bool
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.ReadAll(r.Body) // the connection is only watched once the body is read
+		close(started)
+		<-r.Context().Done()
+		cancelled.Store(true)
+	}))
+	defer srv.Close()
+
+	deps, status := switchableDeps()
+	var errs atomic.Int32
+	n := heartbeat.NewNotifier(heartbeat.Webhook{
+		URL:              srv.URL,
+		ExcludeAggregate: true,
+		OnError:          func(heartbeat.Transition, error) { errs.Add(1) },
+	})
+	r := healthHandler(deps, heartbeat.WithObserver(n))
+
+	status.Store(int32(heartbeat.StatusCritical))
+	healthResponse(t, r)
+	<-started
+	n.Close()
+
+	require.Eventually(t, cancelled.Load, time.Second, time.Millisecond)
+	time.Sleep(10 * time.Millisecond)
+	assert.Zero(t, errs.Load(), "errors of a closed notifier are not reported")
+}